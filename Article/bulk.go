@@ -0,0 +1,137 @@
+package Article
+
+import (
+	"fmt"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"github.com/300brand/go-toml-config"
+	"github.com/300brand/logger"
+	"labix.org/v2/mgo/bson"
+	"sync"
+	"time"
+)
+
+// Only keep this many failures on a job record; the count keeps going
+const maxJobFailures = 500
+
+var (
+	cfgBulkConcurrency    = config.Int("Article.bulk.concurrency", 4)
+	cfgBulkMaxConcurrency = config.Int("Article.bulk.maxconcurrency", 16)
+	// How often job progress is saved and checked for cancellation
+	cfgBulkUpdate = config.Duration("Article.bulk.update", 5*time.Second)
+)
+
+// Starts reprocessing every article matching the query in the background.
+// Use StorageReader.ReprocessJob to follow progress.
+func (s *Service) ReprocessAll(in *types.ReprocessAll, out *types.ReprocessJob) (err error) {
+	query := in.Query
+	if query.Query == nil {
+		query.Query = make(bson.M)
+	}
+	if !in.Dates.Start.IsZero() || !in.Dates.End.IsZero() {
+		published := bson.M{}
+		if !in.Dates.Start.IsZero() {
+			published["$gte"] = in.Dates.Start
+		}
+		if !in.Dates.End.IsZero() {
+			published["$lte"] = in.Dates.End
+		}
+		query.Query["published"] = published
+	}
+	query.Select = bson.M{"_id": 1}
+
+	articles := new(types.MultiArticles)
+	if err = s.client.Call("StorageReader.Articles", query, articles); err != nil {
+		return
+	}
+
+	job := &types.ReprocessJob{
+		Id:          bson.NewObjectId(),
+		Query:       fmt.Sprintf("%v", query.Query),
+		Archived:    in.Archived,
+		Concurrency: in.Concurrency,
+		State:       "running",
+		Total:       len(articles.Articles),
+		Start:       time.Now(),
+	}
+	if job.Concurrency <= 0 {
+		job.Concurrency = *cfgBulkConcurrency
+	}
+	if job.Concurrency > *cfgBulkMaxConcurrency {
+		job.Concurrency = *cfgBulkMaxConcurrency
+	}
+	if err = s.client.Call("StorageWriter.ReprocessJob", job, out); err != nil {
+		return
+	}
+
+	ids := make([]bson.ObjectId, len(articles.Articles))
+	for i, a := range articles.Articles {
+		ids[i] = a.ID
+	}
+	logger.Info.Printf("Article.ReprocessAll: [J:%s] Reprocessing %d articles matching %s", job.Id.Hex(), job.Total, job.Query)
+	go s.runReprocessJob(job, ids)
+	return
+}
+
+func (s *Service) runReprocessJob(job *types.ReprocessJob, ids []bson.ObjectId) {
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		cancelled bool
+		ch        = make(chan bson.ObjectId)
+	)
+
+	for i := 0; i < job.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ch {
+				err := s.Reprocess(&types.Reprocess{Id: id, Archived: job.Archived}, disgo.Null)
+				mu.Lock()
+				job.Processed++
+				if err != nil {
+					job.Failed++
+					if len(job.Failures) < maxJobFailures {
+						job.Failures = append(job.Failures, types.ArticleFailure{Id: id, Error: err.Error()})
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(*cfgBulkUpdate)
+	for i := 0; i < len(ids) && !cancelled; {
+		select {
+		case ch <- ids[i]:
+			i++
+		case <-ticker.C:
+			cancelled = s.saveReprocessJob(job, &mu)
+		}
+	}
+	ticker.Stop()
+	close(ch)
+	wg.Wait()
+
+	job.State, job.Complete = "complete", time.Now()
+	if cancelled {
+		job.State = "cancelled"
+	}
+	s.saveReprocessJob(job, &mu)
+	logger.Info.Printf("Article.ReprocessAll: [J:%s] %s; %d/%d processed, %d failed in %s", job.Id.Hex(), job.State, job.Processed, job.Total, job.Failed, time.Since(job.Start))
+}
+
+// Saves job progress and reports whether the job was cancelled
+func (s *Service) saveReprocessJob(job *types.ReprocessJob, mu *sync.Mutex) (cancelled bool) {
+	mu.Lock()
+	snapshot := *job
+	snapshot.Failures = append([]types.ArticleFailure(nil), job.Failures...)
+	mu.Unlock()
+
+	stored := new(types.ReprocessJob)
+	if err := s.client.Call("StorageWriter.ReprocessJob", &snapshot, stored); err != nil {
+		logger.Error.Printf("Article.ReprocessAll: [J:%s] Saving progress: %s", job.Id.Hex(), err)
+		return
+	}
+	return stored.Cancelled
+}
@@ -0,0 +1,67 @@
+package Article
+
+import (
+	"github.com/300brand/coverage"
+	"github.com/300brand/coverageservices/entity"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"github.com/300brand/go-toml-config"
+	"github.com/300brand/logger"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entity list is maintained through the WebAPI; reload it this often
+var cfgEntitiesRefresh = config.Duration("Article.entities.refresh", 10*time.Minute)
+
+var gazetteer struct {
+	sync.Mutex
+	g      *entity.Gazetteer
+	loaded time.Time
+}
+
+// Finds known organizations, people and places in the article body
+func (s *Service) findEntities(a *coverage.Article) (entities []types.ArticleEntity) {
+	g := s.gazetteer()
+	if g == nil {
+		return
+	}
+	matches := g.Find(a.Text.Body.Text)
+	entities = make([]types.ArticleEntity, len(matches))
+	for i, m := range matches {
+		entities[i] = types.ArticleEntity{
+			Name:  m.Name,
+			Key:   strings.ToLower(m.Name),
+			Type:  m.Type,
+			Count: m.Count,
+		}
+	}
+	s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.Entities", Count: len(entities)}, disgo.Null)
+	return
+}
+
+// Returns the current gazetteer, reloading the entity list when stale. If a
+// reload fails the previous list is kept.
+func (s *Service) gazetteer() *entity.Gazetteer {
+	gazetteer.Lock()
+	defer gazetteer.Unlock()
+
+	if gazetteer.g != nil && time.Since(gazetteer.loaded) < *cfgEntitiesRefresh {
+		return gazetteer.g
+	}
+	gazetteer.loaded = time.Now()
+
+	list := new(types.MultiEntities)
+	if err := s.client.Call("StorageReader.Entities", &types.MultiQuery{}, list); err != nil {
+		logger.Error.Printf("Article.gazetteer: Loading entities: %s", err)
+		return gazetteer.g
+	}
+	g := entity.NewGazetteer()
+	for _, e := range list.Entities {
+		g.Add(e.Name, e.Type, e.Aliases, e.CaseSensitive)
+	}
+	logger.Debug.Printf("Article.gazetteer: Loaded %d entities", len(list.Entities))
+	gazetteer.g = g
+	return g
+}
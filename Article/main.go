@@ -9,19 +9,44 @@ import (
 	"github.com/300brand/coverage/article/published"
 	"github.com/300brand/coverage/article/title"
 	"github.com/300brand/coverage/downloader"
+	"github.com/300brand/coverageservices/archive"
+	"github.com/300brand/coverageservices/metadata"
+	"github.com/300brand/coverageservices/sentiment"
 	"github.com/300brand/coverageservices/service"
+	"github.com/300brand/coverageservices/summary"
 	"github.com/300brand/coverageservices/types"
 	"github.com/300brand/disgo"
+	"github.com/300brand/go-toml-config"
 	"github.com/300brand/logger"
+	"labix.org/v2/mgo/bson"
 	"time"
 )
 
 type Service struct {
-	client *disgo.Client
+	client  caller
+	archive archive.Store
+}
+
+// The parts of *disgo.Client the service uses
+type caller interface {
+	Call(method string, args, reply interface{}) error
 }
 
 var _ service.Service = new(Service)
 
+var (
+	// Keep and process the first part of documents larger than the max file
+	// size instead of dropping them
+	cfgPartial = config.Bool("Article.partial", true)
+	// Storage for raw HTML so articles may be reprocessed without downloading
+	// again. Leave the backend empty to disable archiving.
+	cfgArchiveBackend  = config.String("Article.archive.backend", "")
+	cfgArchiveLocation = config.String("Article.archive.location", "")
+	// "lead" takes the opening sentences, "rank" the most central ones
+	cfgSummaryMethod    = config.String("Article.summary.method", "rank")
+	cfgSummarySentences = config.Int("Article.summary.sentences", 3)
+)
+
 func init() {
 	service.Register("Article", new(Service))
 }
@@ -30,11 +55,50 @@ func init() {
 
 func (s *Service) Start(client *disgo.Client) (err error) {
 	s.client = client
+
+	if *cfgArchiveBackend != "" {
+		if s.archive, err = archive.Open(*cfgArchiveBackend, *cfgArchiveLocation); err != nil {
+			logger.Error.Printf("Article: Failed to open %s archive: %s", *cfgArchiveBackend, err)
+			return
+		}
+		logger.Debug.Printf("Article: Archiving HTML to %s %s", *cfgArchiveBackend, *cfgArchiveLocation)
+	}
 	return
 }
 
 // Service funcs
 
+// Removes archived HTML left behind by purged articles. Keys still in use
+// must be filtered out by the caller. Failures are logged and skipped.
+func (s *Service) ArchiveDelete(in *types.ArchiveKeys, out *disgo.NullType) (err error) {
+	if s.archive == nil {
+		return fmt.Errorf("Archive not configured")
+	}
+	var deleted int
+	for _, key := range in.Keys {
+		if err := s.archive.Delete(key); err != nil {
+			logger.Error.Printf("Article.ArchiveDelete: [%s] %s", key, err)
+			continue
+		}
+		deleted++
+	}
+	logger.Info.Printf("Article.ArchiveDelete: Deleted %d of %d documents", deleted, len(in.Keys))
+	return
+}
+
+// Returns the archived HTML of the article's first page
+func (s *Service) Archived(in *types.ObjectId, out *types.ArchivedHTML) (err error) {
+	if s.archive == nil {
+		return fmt.Errorf("Archive not configured")
+	}
+	ref := new(types.ArticleArchive)
+	if err = s.client.Call("StorageReader.ArticleArchive", in, ref); err != nil {
+		return
+	}
+	out.HTML, err = s.archive.Get(ref.Key)
+	return
+}
+
 func (s *Service) Process(in *coverage.Article, out *disgo.NullType) (err error) {
 	start := time.Now()
 	prefix := fmt.Sprintf("Article.Process: [P:%s] [F:%s] [A:%s] [U:%s]", in.PublicationId.Hex(), in.FeedId.Hex(), in.ID.Hex(), in.URL)
@@ -43,35 +107,177 @@ func (s *Service) Process(in *coverage.Article, out *disgo.NullType) (err error)
 	if err = downloader.Article(in); err != nil {
 		s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.Errors.Download", Count: 1}, disgo.Null)
 		logger.Error.Printf("%s Download error: %s", prefix, err)
+		s.retry(in, err)
 		return
 	}
 
 	defer s.client.Call("StorageWriter.ArticleQueueRemove", &types.ObjectId{in.ID}, disgo.Null)
 
-	if l := len(in.Text.HTML); int64(l) == downloader.MaxFileSize {
-		s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.Errors.DownloadTooBig", Count: 1}, disgo.Null)
-		err = fmt.Errorf("Document larger than max file size (%d)", downloader.MaxFileSize)
-		logger.Error.Printf("%s Download failure: %s", prefix, err)
+	extras := &types.ArticleExtras{Id: in.ID}
+
+	if err = s.limitSize(in, extras, prefix); err != nil {
 		return
 	}
 	s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.HTML.Size", Count: len(in.Text.HTML)}, disgo.Null)
 	logger.Debug.Printf("%s Download success. %d bytes took %s", prefix, len(in.Text.HTML), time.Since(start))
 
+	ref := s.archiveHTML(in, extras, prefix)
+
+	if err = s.extract(in, extras, &pageSource{ref: ref}, prefix, false); err != nil {
+		return
+	}
+
+	schedule := &types.SocialSchedule{
+		ArticleId: in.ID,
+		URL:       in.URL,
+		Published: in.Published,
+	}
+	if err := s.client.Call("Social.Schedule", schedule, disgo.Null); err != nil {
+		logger.Warn.Printf("%s Scheduling social polls: %s", prefix, err)
+	}
+	return
+}
+
+// Runs extraction on an existing article, either downloading it again or
+// using the copy of the HTML stored in the archive. Unlike Process, failures
+// are returned to the caller; the article is never queued, retried or
+// scheduled for social polls.
+func (s *Service) Reprocess(in *types.Reprocess, out *disgo.NullType) (err error) {
+	a := new(coverage.Article)
+	if err = s.client.Call("StorageReader.Article", &types.ObjectId{in.Id}, a); err != nil {
+		return
+	}
+	prefix := fmt.Sprintf("Article.Reprocess: [P:%s] [F:%s] [A:%s] [U:%s]", a.PublicationId.Hex(), a.FeedId.Hex(), a.ID.Hex(), a.URL)
+	extras := &types.ArticleExtras{Id: a.ID}
+	src := new(pageSource)
+
+	if in.Archived {
+		if s.archive == nil {
+			return fmt.Errorf("Archive not configured")
+		}
+		ref := new(types.ArticleArchive)
+		if err = s.client.Call("StorageReader.ArticleArchive", &types.ObjectId{in.Id}, ref); err != nil {
+			return fmt.Errorf("No archived copy of %s: %s", in.Id.Hex(), err)
+		}
+		if a.Text.HTML, err = s.archive.Get(ref.Key); err != nil {
+			return
+		}
+		extras.Truncated = ref.Truncated
+		src.ref, src.archived = ref, true
+		logger.Debug.Printf("%s Using archived copy %s (%d bytes)", prefix, ref.Key, len(a.Text.HTML))
+	} else {
+		if err = downloader.Article(a); err != nil {
+			s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Reprocess.Errors.Download", Count: 1}, disgo.Null)
+			return
+		}
+		if err = s.limitSize(a, extras, prefix); err != nil {
+			return
+		}
+		src.ref = s.archiveHTML(a, extras, prefix)
+	}
+
+	// Start from scratch so the old body does not mask extraction failures
+	a.Text.Body = coverage.Body{}
+	return s.extract(a, extras, src, prefix, true)
+}
+
+// Truncates documents over the publication's max file size, or rejects them
+// when partial processing is off
+func (s *Service) limitSize(a *coverage.Article, extras *types.ArticleExtras, prefix string) (err error) {
+	maxSize := s.maxFileSize(a.PublicationId)
+	if l := int64(len(a.Text.HTML)); l < maxSize {
+		return
+	}
+	if !*cfgPartial {
+		s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.Errors.DownloadTooBig", Count: 1}, disgo.Null)
+		err = fmt.Errorf("Document larger than max file size (%d)", maxSize)
+		logger.Error.Printf("%s Download failure: %s", prefix, err)
+		return
+	}
+	// The headline and opening paragraphs are almost always near the top, so
+	// work with what there is
+	a.Text.HTML = a.Text.HTML[:maxSize]
+	extras.Truncated = true
+	s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.Truncated", Count: 1}, disgo.Null)
+	logger.Warn.Printf("%s Document truncated at %d bytes", prefix, maxSize)
+	return
+}
+
+// Returns the publication's max file size, which may only be smaller than
+// the downloader's own limit
+func (s *Service) maxFileSize(id bson.ObjectId) (max int64) {
+	max = downloader.MaxFileSize
+	if n := s.settings(id).MaxFileSize; n > 0 && n < max {
+		max = n
+	}
+	return
+}
+
+// Stores the downloaded HTML in the archive and records the reference, which
+// is returned so later pages may be added. Failures are logged but do not
+// stop processing.
+func (s *Service) archiveHTML(a *coverage.Article, extras *types.ArticleExtras, prefix string) (ref *types.ArticleArchive) {
+	if s.archive == nil {
+		return
+	}
+	ref = &types.ArticleArchive{
+		Id:        a.ID,
+		Size:      len(a.Text.HTML),
+		Truncated: extras.Truncated,
+	}
+	var err error
+	if ref.Key, err = s.archive.Put(a.Text.HTML); err != nil {
+		s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.Errors.Archive", Count: 1}, disgo.Null)
+		logger.Error.Printf("%s Archive error: %s", prefix, err)
+		return nil
+	}
+	if err = s.client.Call("StorageWriter.ArticleArchive", ref, disgo.Null); err != nil {
+		logger.Error.Printf("%s Archive reference: %s", prefix, err)
+		return nil
+	}
+	return
+}
+
+// Extracts author, date, body, words and keywords from the article's HTML
+// and saves the result. Reprocessed articles were already counted toward
+// their publication, so they are saved without touching the counters.
+func (s *Service) extract(in *coverage.Article, extras *types.ArticleExtras, pages *pageSource, prefix string, reprocess bool) (err error) {
+	save := "StorageWriter.Article"
+	if reprocess {
+		save = "StorageWriter.ArticleUpdate"
+	}
 	// If any step fails along the way, save the article's state
 	defer func() {
-		if err = s.client.Call("StorageWriter.Article", in, in); err != nil {
+		if err = s.client.Call(save, in, in); err != nil {
 			s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.Errors.Database", Count: 1}, disgo.Null)
 			logger.Error.Printf("%s Error saving: %s", prefix, err)
+			return
+		}
+		// Extras live outside of coverage.Article, so they go in after the
+		// article itself is saved
+		if err = s.client.Call("StorageWriter.ArticleExtras", extras, disgo.Null); err != nil {
+			logger.Error.Printf("%s Error saving extras: %s", prefix, err)
 		}
 	}()
 
 	// Apply XPaths from pub
-	start = time.Now()
+	start := time.Now()
 
-	if err = s.applyXPaths(in); err != nil {
+	// Don't really like this as it adds another query into the DB, but we'll
+	// see how it goes. Without the pub there are no XPaths, but the
+	// fallbacks still apply.
+	pub := new(coverage.Publication)
+	if err = s.client.Call("StorageReader.Publication", types.ObjectId{in.PublicationId}, pub); err != nil {
+		logger.Error.Printf("%s Fetch publication: %s", prefix, err)
+	}
+
+	if err = s.applyXPaths(in, pub, extras); err != nil {
 		logger.Error.Printf("%s %s", prefix, err)
 	}
 
+	// Pull in the rest of multi-page articles before counting words
+	s.stitchPages(in, pub, extras, pages, prefix)
+
 	s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.Body.Size", Count: len(in.Text.Body.Text)}, disgo.Null)
 
 	// Filter out individual words
@@ -82,19 +288,36 @@ func (s *Service) Process(in *coverage.Article, out *disgo.NullType) (err error)
 	in.Text.Words.Keywords = lexer.Keywords(in.Text.Body.Text)
 	s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.Body.Keywords", Count: len(in.Text.Words.Keywords)}, disgo.Null)
 
+	// Organizations, people and places from the entity list
+	extras.Entities = s.findEntities(in)
+
+	extras.Sentiment = sentiment.Score(string(in.Text.Body.Text))
+
+	switch *cfgSummaryMethod {
+	case "lead":
+		extras.Summary = summary.Lead(string(in.Text.Body.Text), *cfgSummarySentences)
+	default:
+		extras.Summary = summary.Rank(string(in.Text.Body.Text), *cfgSummarySentences)
+	}
+
 	s.client.Call("Stats.Duration", &types.Stat{Name: "Article.Process", Duration: time.Since(start)}, disgo.Null)
-	logger.Debug.Printf("%s Body Length: %d; Words: %d; Keywords: %d; Took: %s", prefix, len(in.Text.Body.Text), len(in.Text.Words.All), len(in.Text.Words.Keywords), time.Since(start))
+	logger.Debug.Printf("%s Body Length: %d; Words: %d; Keywords: %d; Entities: %d; Sentiment: %.3f; Took: %s", prefix, len(in.Text.Body.Text), len(in.Text.Words.All), len(in.Text.Words.Keywords), len(extras.Entities), extras.Sentiment, time.Since(start))
 
 	return
 }
 
-func (s *Service) applyXPaths(a *coverage.Article) (err error) {
+func (s *Service) applyXPaths(a *coverage.Article, pub *coverage.Publication, extras *types.ArticleExtras) (err error) {
 	prefix := fmt.Sprintf("Article.ApplyXPaths: [P:%s] [F:%s] [A:%s] [U:%s]", a.PublicationId.Hex(), a.FeedId.Hex(), a.ID.Hex(), a.URL)
-	// Don't really like this as it adds another query into the DB, but we'll
-	// see how it goes
-	pub := new(coverage.Publication)
-	if err = s.client.Call("StorageReader.Publication", types.ObjectId{a.PublicationId}, pub); err != nil {
-		return fmt.Errorf("Fetch publication: %s", err)
+
+	// JSON-LD, OpenGraph and meta tags fill in anything the XPaths could
+	// not. Only parsed when needed.
+	var meta *metadata.Metadata
+	getMeta := func() *metadata.Metadata {
+		if meta == nil {
+			m := metadata.Extract(a.Text.HTML)
+			meta = &m
+		}
+		return meta
 	}
 
 	// Authors
@@ -115,6 +338,12 @@ func (s *Service) applyXPaths(a *coverage.Article) (err error) {
 		}
 	}(pub.XPaths.Author)
 
+	if a.Author == "" {
+		if a.Author = getMeta().Author; a.Author != "" {
+			s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Author.Metadata", Count: 1}, disgo.Null)
+		}
+	}
+
 	// Published Date
 	func(xpaths []string) {
 		// If the date is already set from the feed, skip this bit
@@ -137,6 +366,12 @@ func (s *Service) applyXPaths(a *coverage.Article) (err error) {
 		}
 	}(pub.XPaths.Date)
 
+	if a.Published.IsZero() {
+		if a.Published = getMeta().Published; !a.Published.IsZero() {
+			s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Date.Metadata", Count: 1}, disgo.Null)
+		}
+	}
+
 	// Body
 	func(xpaths []string) {
 		if len(xpaths) > 0 {
@@ -183,5 +418,16 @@ func (s *Service) applyXPaths(a *coverage.Article) (err error) {
 		}
 	}(pub.XPaths.Title)
 
+	if a.Title == "" {
+		if a.Title = getMeta().Title; a.Title != "" {
+			s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Title.Metadata", Count: 1}, disgo.Null)
+		}
+	}
+
+	// No XPaths for images, metadata is the only source
+	if extras.Image = getMeta().Image; extras.Image != "" {
+		s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Image.Found", Count: 1}, disgo.Null)
+	}
+
 	return
 }
@@ -0,0 +1,172 @@
+package Article
+
+import (
+	"fmt"
+	"github.com/300brand/coverage"
+	"github.com/300brand/coverage/article/body"
+	"github.com/300brand/coverage/downloader"
+	"github.com/300brand/coverageservices/metadata"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"github.com/300brand/go-toml-config"
+	"github.com/300brand/logger"
+	"github.com/moovweb/gokogiri"
+	"github.com/moovweb/gokogiri/xml"
+	"net/url"
+	"strings"
+)
+
+// Total pages fetched for a single article, including the first
+var cfgMaxPages = config.Int("Article.pages.max", 10)
+
+// Where pages after the first come from. Downloaded pages are added to the
+// archive alongside the first page when ref is set; archived pages are only
+// ever read back out of it.
+type pageSource struct {
+	ref      *types.ArticleArchive
+	archived bool
+}
+
+// Follows next-page links and appends each page's body to the article. Page
+// one's body must already be extracted.
+func (s *Service) stitchPages(a *coverage.Article, pub *coverage.Publication, extras *types.ArticleExtras, src *pageSource, prefix string) {
+	xpaths := s.settings(a.PublicationId).NextPage
+
+	html, pageURL := a.Text.HTML, a.URL
+	seen := map[string]bool{a.URL: true}
+	for len(seen) < *cfgMaxPages {
+		next := nextPage(html, pageURL, xpaths)
+		if next == "" || seen[next] {
+			break
+		}
+		seen[next] = true
+
+		page := &coverage.Article{
+			ID:            a.ID,
+			FeedId:        a.FeedId,
+			PublicationId: a.PublicationId,
+			URL:           next,
+		}
+		if err := s.fetchPage(page, src); err != nil {
+			s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Pages.Errors.Download", Count: 1}, disgo.Null)
+			logger.Error.Printf("%s Page %d fetch error [%s]: %s", prefix, len(seen), next, err)
+			break
+		}
+		if len(pub.XPaths.Body) > 0 {
+			body.XPath(page.Text.HTML, pub.XPaths.Body, &page.Text.Body)
+		}
+		if len(page.Text.Body.Text) == 0 {
+			body.SetBody(page)
+		}
+		if len(page.Text.Body.Text) == 0 {
+			s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Pages.Errors.BodyExtraction", Count: 1}, disgo.Null)
+			logger.Error.Printf("%s Page %d has no body [%s]", prefix, len(seen), next)
+			break
+		}
+
+		a.Text.Body.Text = append(append(a.Text.Body.Text, "\n\n"...), page.Text.Body.Text...)
+		a.Text.Body.HTML = append(append(a.Text.Body.HTML, "\n\n"...), page.Text.Body.HTML...)
+		extras.Pages = append(extras.Pages, next)
+		html, pageURL = page.Text.HTML, next
+	}
+
+	if !src.archived && src.ref != nil && len(src.ref.Pages) > 0 {
+		if err := s.client.Call("StorageWriter.ArticleArchive", src.ref, disgo.Null); err != nil {
+			logger.Error.Printf("%s Archive reference: %s", prefix, err)
+		}
+	}
+
+	if len(extras.Pages) == 0 {
+		return
+	}
+	extras.Pages = append([]string{a.URL}, extras.Pages...)
+	s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Pages", Count: len(extras.Pages)}, disgo.Null)
+	logger.Debug.Printf("%s Stitched %d pages", prefix, len(extras.Pages))
+}
+
+// Fills in the page's HTML from the archive, or downloads it and adds it to
+// the archive
+func (s *Service) fetchPage(page *coverage.Article, src *pageSource) (err error) {
+	if src.archived {
+		for _, p := range src.ref.Pages {
+			if p.URL == page.URL {
+				page.Text.HTML, err = s.archive.Get(p.Key)
+				return
+			}
+		}
+		return fmt.Errorf("Page not archived")
+	}
+
+	if err = downloader.Article(page); err != nil || src.ref == nil {
+		return
+	}
+	key, err := s.archive.Put(page.Text.HTML)
+	if err != nil {
+		s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.Errors.Archive", Count: 1}, disgo.Null)
+		// The page itself is fine; it just won't be there to reprocess
+		return nil
+	}
+	src.ref.Pages = append(src.ref.Pages, types.ArchivedPage{URL: page.URL, Key: key})
+	return
+}
+
+// Finds the absolute URL of the next page using the publication's XPaths
+// (which should select a link or its href), falling back to rel="next" links.
+// Links off the page's host are ignored.
+func nextPage(html []byte, base string, xpaths []string) string {
+	var href string
+	if len(xpaths) > 0 {
+		href = xpathHref(html, xpaths)
+	}
+	if href == "" {
+		href = metadata.NextPage(html)
+	}
+	if href == "" {
+		return ""
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	next := baseURL.ResolveReference(ref)
+	if next.Scheme != "http" && next.Scheme != "https" {
+		return ""
+	}
+	if !strings.EqualFold(next.Host, baseURL.Host) {
+		return ""
+	}
+	next.Fragment = ""
+	return next.String()
+}
+
+// Returns the href of the first link any of the XPaths selects. XPaths may
+// select the a element itself or its href attribute.
+func xpathHref(html []byte, xpaths []string) string {
+	doc, err := gokogiri.ParseHtml(html)
+	if err != nil {
+		return ""
+	}
+	defer doc.Free()
+
+	for _, xpath := range xpaths {
+		nodes, err := doc.Search(xpath)
+		if err != nil {
+			continue
+		}
+		for _, n := range nodes {
+			href := n.Attr("href")
+			if n.NodeType() == xml.XML_ATTRIBUTE_NODE {
+				href = n.Content()
+			}
+			if href = strings.TrimSpace(href); href != "" {
+				return href
+			}
+		}
+	}
+	return ""
+}
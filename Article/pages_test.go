@@ -0,0 +1,23 @@
+package Article
+
+import (
+	"testing"
+)
+
+func TestNextPage(t *testing.T) {
+	base := "http://example.com/news/story?page=1"
+	for _, test := range []struct {
+		HTML string
+		Next string
+	}{
+		{`<head><link rel="next" href="story?page=2#top"></head>`, "http://example.com/news/story?page=2"},
+		{`<head><link rel="next" href="//EXAMPLE.com/news/story?page=2"></head>`, "http://EXAMPLE.com/news/story?page=2"},
+		{`<head><link rel="next" href="http://other.com/news/story?page=2"></head>`, ""},
+		{`<head><link rel="next" href="javascript:void(0)"></head>`, ""},
+		{`<head></head>`, ""},
+	} {
+		if next := nextPage([]byte(test.HTML), base, nil); next != test.Next {
+			t.Errorf("%s: Expected %q, got %q", test.HTML, test.Next, next)
+		}
+	}
+}
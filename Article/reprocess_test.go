@@ -0,0 +1,182 @@
+package Article
+
+import (
+	"github.com/300brand/coverage"
+	"github.com/300brand/coverageservices/archive"
+	"github.com/300brand/coverageservices/types"
+	"io/ioutil"
+	"labix.org/v2/mgo/bson"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testHTML = `<html><head><title>Widgets</title></head><body><article>
+<p>Widget sales rose again this quarter as the company opened new stores.</p>
+<p>Analysts expect the trend to continue through the end of the year.</p>
+</article></body></html>`
+
+// Answers the RPCs the Article service makes, keeping publication counters
+// the way StorageWriter does
+type fakeClient struct {
+	mu          sync.Mutex
+	article     coverage.Article
+	archiveKey  string
+	pages       []types.ArchivedPage
+	numArticles int
+	calls       []string
+}
+
+func (f *fakeClient) Call(method string, args, reply interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, method)
+	switch method {
+	case "StorageReader.Article":
+		*reply.(*coverage.Article) = f.article
+	case "StorageReader.ArticleArchive":
+		*reply.(*types.ArticleArchive) = types.ArticleArchive{Id: f.article.ID, Key: f.archiveKey, Pages: f.pages}
+	case "StorageWriter.Article":
+		// StorageWriter.Article always increments the publication
+		f.numArticles++
+		*reply.(*coverage.Article) = *args.(*coverage.Article)
+	case "StorageWriter.ArticleUpdate":
+		*reply.(*coverage.Article) = *args.(*coverage.Article)
+	}
+	return nil
+}
+
+func (f *fakeClient) called(prefix string) (methods []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, m := range f.calls {
+		if strings.HasPrefix(m, prefix) {
+			methods = append(methods, m)
+		}
+	}
+	return
+}
+
+func TestReprocessKeepsCounters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := archive.Open("local", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := store.Put([]byte(testHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &fakeClient{
+		article: coverage.Article{
+			ID:            bson.NewObjectId(),
+			PublicationId: bson.NewObjectId(),
+			URL:           "http://example.com/widgets",
+		},
+		archiveKey: key,
+	}
+	s := &Service{client: client, archive: store}
+
+	for i := 0; i < 3; i++ {
+		if err := s.Reprocess(&types.Reprocess{Id: client.article.ID, Archived: true}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if client.numArticles != 0 {
+		t.Errorf("Reprocessing changed NumArticles by %d", client.numArticles)
+	}
+	if saves := client.called("StorageWriter.ArticleUpdate"); len(saves) != 3 {
+		t.Errorf("Expected 3 saves through ArticleUpdate, got %d", len(saves))
+	}
+}
+
+func TestReprocessArchivedPages(t *testing.T) {
+	var hits int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte(testHTML))
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "article")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := archive.Open("local", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := strings.Replace(testHTML, "</head>", `<link rel="next" href="/widgets?page=2"></head>`, 1)
+	key, err := store.Put([]byte(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pageKey, err := store.Put([]byte(testHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &fakeClient{
+		article: coverage.Article{
+			ID:            bson.NewObjectId(),
+			PublicationId: bson.NewObjectId(),
+			URL:           ts.URL + "/widgets",
+		},
+		archiveKey: key,
+		pages:      []types.ArchivedPage{{URL: ts.URL + "/widgets?page=2", Key: pageKey}},
+	}
+	s := &Service{client: client, archive: store}
+	if err := s.Reprocess(&types.Reprocess{Id: client.article.ID, Archived: true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if hits > 0 {
+		t.Errorf("Archived reprocess made %d requests", hits)
+	}
+	if calls := client.called("StorageWriter.ArticleArchive"); len(calls) > 0 {
+		t.Errorf("Archived reprocess archived pages again")
+	}
+}
+
+func TestReprocessNoSideEffects(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/widgets" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(testHTML))
+	}))
+	defer ts.Close()
+
+	for _, url := range []string{ts.URL + "/widgets", ts.URL + "/missing"} {
+		client := &fakeClient{
+			article: coverage.Article{
+				ID:            bson.NewObjectId(),
+				PublicationId: bson.NewObjectId(),
+				URL:           url,
+			},
+		}
+		s := &Service{client: client}
+		s.Reprocess(&types.Reprocess{Id: client.article.ID}, nil)
+
+		for _, prefix := range []string{"StorageWriter.ArticleQueue", "StorageWriter.ArticleDeadLetter", "Social."} {
+			if calls := client.called(prefix); len(calls) > 0 {
+				t.Errorf("%s: Reprocess called %v", url, calls)
+			}
+		}
+		if client.numArticles != 0 {
+			t.Errorf("%s: Reprocessing changed NumArticles by %d", url, client.numArticles)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package Article
+
+import (
+	"github.com/300brand/coverage"
+	"github.com/300brand/coverageservices/backoff"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"github.com/300brand/go-toml-config"
+	"github.com/300brand/logger"
+	"time"
+)
+
+var (
+	// Number of failed attempts before an article is moved to the dead-letter
+	// collection
+	cfgRetryAttempts = config.Int("Article.retry.attempts", 5)
+	// Delay after the first failure, doubled after every failure thereafter
+	cfgRetryBackoff    = config.Duration("Article.retry.backoff", 5*time.Minute)
+	cfgRetryMaxBackoff = config.Duration("Article.retry.maxbackoff", 24*time.Hour)
+)
+
+// Moves a dead-lettered article back into the processing queue
+func (s *Service) Requeue(in *types.ObjectId, out *disgo.NullType) (err error) {
+	dead := new(types.DeadArticle)
+	if err = s.client.Call("StorageReader.DeadArticle", in, dead); err != nil {
+		return
+	}
+	dead.Article.Dequeue = time.Now()
+	if err = s.client.Call("StorageWriter.ArticleQueueAdd", &dead.Article, disgo.Null); err != nil {
+		return
+	}
+	return s.client.Call("StorageWriter.DeadArticleRemove", in, disgo.Null)
+}
+
+// Records the failure against the queued article and either pushes it back
+// in the queue or gives up on it entirely
+func (s *Service) retry(a *coverage.Article, cause error) {
+	attempts := new(types.ArticleAttempts)
+	failure := &types.ArticleFailure{Id: a.ID, Error: cause.Error()}
+	if err := s.client.Call("StorageWriter.ArticleQueueFail", failure, attempts); err != nil {
+		logger.Error.Printf("Article.retry: [A:%s] Recording failure: %s", a.ID.Hex(), err)
+		return
+	}
+
+	if attempts.Attempts >= *cfgRetryAttempts {
+		s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.DeadLetter", Count: 1}, disgo.Null)
+		logger.Warn.Printf("Article.retry: [A:%s] Giving up after %d attempts: %s", a.ID.Hex(), attempts.Attempts, cause)
+		dead := &types.DeadArticle{
+			Article:  *a,
+			Attempts: attempts.Attempts,
+			Error:    attempts.Error,
+		}
+		if err := s.client.Call("StorageWriter.ArticleDeadLetter", dead, disgo.Null); err != nil {
+			logger.Error.Printf("Article.retry: [A:%s] Dead-lettering: %s", a.ID.Hex(), err)
+		}
+		return
+	}
+
+	delay := backoff.Delay(attempts.Attempts, *cfgRetryBackoff, *cfgRetryMaxBackoff)
+	a.Dequeue = time.Now().Add(delay)
+	s.client.Call("Stats.Increment", &types.Stat{Name: "Article.Process.Retry", Count: 1}, disgo.Null)
+	logger.Debug.Printf("Article.retry: [A:%s] Attempt %d failed, retrying in %s", a.ID.Hex(), attempts.Attempts, delay)
+	if err := s.client.Call("StorageWriter.ArticleQueueDelay", a, disgo.Null); err != nil {
+		logger.Error.Printf("Article.retry: [A:%s] Delaying: %s", a.ID.Hex(), err)
+	}
+}
@@ -0,0 +1,49 @@
+package Article
+
+import (
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/go-toml-config"
+	"github.com/300brand/logger"
+	"labix.org/v2/mgo/bson"
+	"sync"
+	"time"
+)
+
+// Publication settings change rarely; reload each publication's this often
+var cfgSettingsRefresh = config.Duration("Article.settings.refresh", 5*time.Minute)
+
+type cachedSettings struct {
+	settings types.PubSettings
+	loaded   time.Time
+}
+
+var pubSettings struct {
+	sync.Mutex
+	m map[bson.ObjectId]cachedSettings
+}
+
+// Returns the publication's settings, reloading them when stale. If a reload
+// fails the previous settings are kept.
+func (s *Service) settings(id bson.ObjectId) types.PubSettings {
+	pubSettings.Lock()
+	defer pubSettings.Unlock()
+
+	if pubSettings.m == nil {
+		pubSettings.m = make(map[bson.ObjectId]cachedSettings)
+	}
+	cached, ok := pubSettings.m[id]
+	if ok && time.Since(cached.loaded) < *cfgSettingsRefresh {
+		return cached.settings
+	}
+	cached.loaded = time.Now()
+
+	settings := new(types.PubSettings)
+	if err := s.client.Call("StorageReader.PublicationSettings", &types.ObjectId{id}, settings); err != nil {
+		logger.Warn.Printf("Article.settings: [P:%s] %s", id.Hex(), err)
+		pubSettings.m[id] = cached
+		return cached.settings
+	}
+	cached.settings = *settings
+	pubSettings.m[id] = cached
+	return cached.settings
+}
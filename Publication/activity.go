@@ -0,0 +1,45 @@
+package Publication
+
+import (
+	"fmt"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/go-toml-config"
+)
+
+const dayLayout = "2006-01-02"
+
+var (
+	cfgActivityDays    = config.Int("Publication.activity.days", 30)
+	cfgActivityMaxDays = config.Int("Publication.activity.maxdays", 365)
+)
+
+// Articles per day, per-feed activity, body extraction success and the most
+// recent article over the last in.Days days. Download failures are reported
+// separately as DeadLettered.
+func (s *Service) Activity(in *types.ActivityQuery, out *types.PubActivity) (err error) {
+	if in.Days <= 0 {
+		in.Days = *cfgActivityDays
+	}
+	if in.Days > *cfgActivityMaxDays {
+		return fmt.Errorf("Days cannot exceed %d", *cfgActivityMaxDays)
+	}
+	if err = s.client.Call("StorageReader.PublicationActivity", in, out); err != nil {
+		return
+	}
+
+	// Fill in days without articles
+	counts := make(map[string]int, len(out.Days))
+	for _, d := range out.Days {
+		counts[d.Date.Format(dayLayout)] = d.Articles
+	}
+	out.Days = make([]types.DayCount, in.Days)
+	for i := range out.Days {
+		date := out.Since.AddDate(0, 0, i)
+		out.Days[i] = types.DayCount{Date: date, Articles: counts[date.Format(dayLayout)]}
+	}
+
+	if total := out.Extracted + out.Failed; total > 0 {
+		out.Success = float64(out.Extracted) / float64(total)
+	}
+	return
+}
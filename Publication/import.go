@@ -0,0 +1,188 @@
+package Publication
+
+import (
+	"fmt"
+	"github.com/300brand/coverage"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"labix.org/v2/mgo/bson"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+	ImportError     = "error"
+)
+
+// Imports publications by URL: new URLs are created, existing publications get
+// the title, readership and any feeds they are missing. Safe to run again with
+// the same list. Failures are reported per publication and do not stop the
+// rest of the import.
+func (s *Service) AddAll(in *types.PubImport, out *types.PubImportResults) (err error) {
+	out.DryRun = in.DryRun
+	out.Results = make([]types.PubImportResult, len(in.Pubs))
+	seen := make(map[string]int, len(in.Pubs))
+	for i := range in.Pubs {
+		pub := &in.Pubs[i]
+		pub.URL = strings.TrimSpace(pub.URL)
+		r := &out.Results[i]
+		r.URL = pub.URL
+
+		// Every repeat is reported against the first row, which is the one
+		// imported
+		key := importKey(pub.URL)
+		if first, ok := seen[key]; ok {
+			r.Status, r.Error = ImportError, fmt.Sprintf("Duplicate of publication %d in this import", first)
+		} else {
+			seen[key] = i
+			if err := s.importPub(pub, r, in.DryRun); err != nil {
+				r.Status, r.Error = ImportError, err.Error()
+			}
+		}
+
+		switch r.Status {
+		case ImportCreated:
+			out.Created++
+		case ImportUpdated:
+			out.Updated++
+		case ImportUnchanged:
+			out.Unchanged++
+		case ImportError:
+			out.Errors++
+		}
+	}
+	return
+}
+
+func (s *Service) importPub(in *types.Pub, r *types.PubImportResult, dryRun bool) (err error) {
+	if err = validURL(in.URL); err != nil {
+		return fmt.Errorf("URL: %s", err)
+	}
+	for i, feedUrl := range in.Feeds {
+		if err = validURL(feedUrl); err != nil {
+			return fmt.Errorf("Feed %d: %s", i, err)
+		}
+	}
+
+	existing, err := s.findByURL(in.URL)
+	if err != nil {
+		return
+	}
+	if existing == nil {
+		r.Status, r.NewFeeds = ImportCreated, in.Feeds
+		if dryRun {
+			return
+		}
+		p := new(coverage.Publication)
+		if err = s.Add(in, p); err != nil {
+			return
+		}
+		r.Id = p.ID
+		return
+	}
+	r.Id = existing.ID
+
+	fields := make(map[string]interface{})
+	if title := strings.TrimSpace(in.Title); title != "" && title != existing.Title {
+		fields["title"] = title
+	}
+	if in.Readership > 0 && in.Readership != existing.NumReaders {
+		fields["numreaders"] = in.Readership
+	}
+	if r.NewFeeds, err = s.missingFeeds(existing.ID, in.Feeds); err != nil {
+		return
+	}
+	for k := range fields {
+		r.Changed = append(r.Changed, k)
+	}
+	sort.Strings(r.Changed)
+
+	if len(fields) == 0 && len(r.NewFeeds) == 0 {
+		r.Status = ImportUnchanged
+		return
+	}
+	r.Status = ImportUpdated
+	if dryRun {
+		return
+	}
+	if len(fields) > 0 {
+		if err = s.Update(&types.PubUpdate{Id: existing.ID, Fields: fields}, disgo.Null); err != nil {
+			return
+		}
+	}
+	for _, feedUrl := range r.NewFeeds {
+		newFeed := &types.NewFeed{PublicationId: existing.ID, URL: feedUrl}
+		if err = s.client.Call("Feed.Add", newFeed, new(coverage.Feed)); err != nil {
+			return fmt.Errorf("Adding feed %s: %s", feedUrl, err)
+		}
+	}
+	return
+}
+
+// Identifies a publication URL regardless of the case of its scheme and host
+// or a trailing slash
+func importKey(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u
+	}
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Path = strings.TrimRight(parsed.Path, "/")
+	return parsed.String()
+}
+
+// Returns nil if no publication has the URL. Removed publications must be
+// restored before importing into them.
+func (s *Service) findByURL(u string) (p *coverage.Publication, err error) {
+	query := &types.MultiQuery{
+		Query: bson.M{"url": u, "deleted": bson.M{"$exists": false}},
+		Limit: 1,
+	}
+	pubs := new(types.MultiPubs)
+	if err = s.client.Call("StorageReader.Publications", query, pubs); err != nil {
+		return
+	}
+	if len(pubs.Publications) > 0 {
+		return pubs.Publications[0], nil
+	}
+
+	query.Query["deleted"] = bson.M{"$exists": true}
+	if err = s.client.Call("StorageReader.Publications", query, pubs); err != nil {
+		return
+	}
+	if len(pubs.Publications) > 0 {
+		return nil, fmt.Errorf("Publication %s has been removed", pubs.Publications[0].ID.Hex())
+	}
+	return
+}
+
+// Feed URLs not yet on the publication, ignoring deleted feeds
+func (s *Service) missingFeeds(pubId bson.ObjectId, urls []string) (missing []string, err error) {
+	if len(urls) == 0 {
+		return
+	}
+	query := &types.MultiQuery{
+		Query:  bson.M{"publicationid": pubId, "deleted": false},
+		Select: bson.M{"url": 1},
+	}
+	feeds := new(types.MultiFeeds)
+	if err = s.client.Call("StorageReader.Feeds", query, feeds); err != nil {
+		return
+	}
+	have := make(map[string]bool, len(feeds.Feeds))
+	for _, f := range feeds.Feeds {
+		have[f.URL] = true
+	}
+	for _, u := range urls {
+		if !have[u] {
+			missing = append(missing, u)
+			have[u] = true
+		}
+	}
+	return
+}
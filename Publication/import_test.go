@@ -0,0 +1,24 @@
+package Publication
+
+import (
+	"testing"
+)
+
+func TestImportKey(t *testing.T) {
+	for _, same := range [][2]string{
+		{"http://example.com", "http://example.com/"},
+		{"HTTP://Example.COM/news/", "http://example.com/news"},
+	} {
+		if a, b := importKey(same[0]), importKey(same[1]); a != b {
+			t.Errorf("%s and %s: Expected the same key, got %s and %s", same[0], same[1], a, b)
+		}
+	}
+	for _, different := range [][2]string{
+		{"http://example.com/News", "http://example.com/news"},
+		{"http://example.com", "https://example.com"},
+	} {
+		if importKey(different[0]) == importKey(different[1]) {
+			t.Errorf("%s and %s: Expected different keys", different[0], different[1])
+		}
+	}
+}
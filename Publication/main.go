@@ -7,10 +7,9 @@ import (
 	"github.com/300brand/disgo"
 	"labix.org/v2/mgo/bson"
 	"net/url"
+	"time"
 )
 
-type PubsArr []types.Pub
-
 type Service struct {
 	client *disgo.Client
 }
@@ -25,6 +24,9 @@ func init() {
 
 func (s *Service) Start(client *disgo.Client) (err error) {
 	s.client = client
+	if *cfgReconcileInterval > 0 {
+		go s.reconcileLoop()
+	}
 	return
 }
 
@@ -48,6 +50,17 @@ func (s *Service) Add(in *types.Pub, out *coverage.Publication) (err error) {
 	if err = s.client.Call("StorageWriter.Publication", p, disgo.Null); err != nil {
 		return
 	}
+	if p.NumReaders > 0 {
+		entry := &types.Readership{
+			PublicationId: p.ID,
+			Readers:       p.NumReaders,
+			Effective:     p.Added,
+			Added:         time.Now(),
+		}
+		if err = s.client.Call("StorageWriter.Readership", entry, disgo.Null); err != nil {
+			return
+		}
+	}
 	for _, f := range feeds {
 		if err = s.client.Call("StorageWriter.Feed", f, disgo.Null); err != nil {
 			continue
@@ -57,16 +70,6 @@ func (s *Service) Add(in *types.Pub, out *coverage.Publication) (err error) {
 	return
 }
 
-func (s *Service) AddAll(in *PubsArr, out *disgo.NullType) (err error) {
-	p := new(coverage.Publication)
-	for _, pub := range []types.Pub(*in) {
-		if err = s.Add(&pub, p); err != nil {
-			return
-		}
-	}
-	return
-}
-
 func (s *Service) View(in *types.ViewPubQuery, out *types.ViewPub) (err error) {
 	pubId := &types.ObjectId{Id: in.Publication}
 	if err = s.client.Call("StorageReader.Publication", pubId, &out.Publication); err != nil {
@@ -0,0 +1,61 @@
+package Publication
+
+import (
+	"fmt"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/logger"
+	"labix.org/v2/mgo/bson"
+)
+
+// Folds duplicate publications into the target. Feeds, articles, saved
+// searches, readership history and tags move over, counts are recalculated
+// and each source ID keeps resolving to the target through
+// StorageReader.Publication.
+func (s *Service) Merge(in *types.PubMerge, out *types.PubMergeResult) (err error) {
+	if len(in.Sources) == 0 {
+		return fmt.Errorf("No source publications to merge")
+	}
+	seen := map[bson.ObjectId]bool{in.Target: true}
+	for _, id := range in.Sources {
+		if seen[id] {
+			return fmt.Errorf("Publication %s listed more than once", id.Hex())
+		}
+		seen[id] = true
+	}
+
+	// Target must be live; sources may be removed, but must still exist and
+	// not already be merged elsewhere
+	ids := append([]bson.ObjectId{in.Target}, in.Sources...)
+	query := &types.MultiQuery{
+		Query: bson.M{"_id": bson.M{"$in": ids}},
+	}
+	pubs := new(types.MultiPubs)
+	if err = s.client.Call("StorageReader.Publications", query, pubs); err != nil {
+		return
+	}
+	found := make(map[bson.ObjectId]bool, len(pubs.Publications))
+	for _, p := range pubs.Publications {
+		found[p.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return fmt.Errorf("Publication %s not found", id.Hex())
+		}
+	}
+	removed := &types.MultiQuery{
+		Query: bson.M{"_id": in.Target, "deleted": bson.M{"$exists": true}},
+	}
+	removedPubs := new(types.MultiPubs)
+	if err = s.client.Call("StorageReader.Publications", removed, removedPubs); err != nil {
+		return
+	}
+	if removedPubs.Total > 0 {
+		return fmt.Errorf("Target publication %s has been removed", in.Target.Hex())
+	}
+
+	if err = s.client.Call("StorageWriter.PublicationMerge", in, out); err != nil {
+		return
+	}
+	logger.Info.Printf("Publication.Merge: [P:%s] Merged %d publications; moved %d feeds, %d articles, %d queued, %d readership entries; updated %d searches", in.Target.Hex(), len(in.Sources), out.Feeds, out.Articles, out.QueuedArticles, out.Readership, out.Searches)
+	return
+}
@@ -0,0 +1,69 @@
+package Publication
+
+import (
+	"fmt"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"github.com/300brand/logger"
+	"labix.org/v2/mgo/bson"
+	"time"
+)
+
+// Records readership entries. Each update is checked on its own so one bad row
+// in a bulk import does not stop the rest. Publications whose current
+// readership changed get NumReaders updated to match.
+func (s *Service) SetReadership(in *types.ReadershipUpdates, out *types.ReadershipResults) (err error) {
+	now := time.Now()
+	touched := make(map[bson.ObjectId]bool)
+	for i, u := range in.Updates {
+		if err := s.saveReadership(&u, now); err != nil {
+			out.Errors = append(out.Errors, types.ReadershipError{Index: i, Error: err.Error()})
+			continue
+		}
+		out.Saved++
+		touched[u.PublicationId] = true
+	}
+
+	for id := range touched {
+		if err := s.syncNumReaders(id, now); err != nil {
+			logger.Error.Printf("Publication.SetReadership: [P:%s] Updating NumReaders: %s", id.Hex(), err)
+		}
+	}
+	return
+}
+
+func (s *Service) saveReadership(u *types.ReadershipUpdate, now time.Time) (err error) {
+	if u.Readers < 0 {
+		return fmt.Errorf("Readers cannot be negative")
+	}
+	if u.Effective.IsZero() {
+		u.Effective = now
+	}
+	pub := &types.MultiQuery{Query: bson.M{"_id": u.PublicationId}}
+	pubs := new(types.MultiPubs)
+	if err = s.client.Call("StorageReader.Publications", pub, pubs); err != nil {
+		return
+	}
+	if pubs.Total == 0 {
+		return fmt.Errorf("Publication %s not found", u.PublicationId.Hex())
+	}
+	entry := &types.Readership{
+		PublicationId: u.PublicationId,
+		Readers:       u.Readers,
+		Effective:     u.Effective,
+		Added:         now,
+	}
+	return s.client.Call("StorageWriter.Readership", entry, disgo.Null)
+}
+
+// Sets NumReaders to the readership in effect now. Goes straight to storage
+// since Update would record the value in the history a second time.
+func (s *Service) syncNumReaders(id bson.ObjectId, now time.Time) (err error) {
+	lookup := &types.ReadershipLookups{Lookups: []types.ReadershipLookup{{PublicationId: id, Date: now}}}
+	values := new(types.ReadershipValues)
+	if err = s.client.Call("StorageReader.ReadershipAt", lookup, values); err != nil {
+		return
+	}
+	changes := &types.PubChanges{Id: id, NumReaders: &values.Readers[0]}
+	return s.client.Call("StorageWriter.UpdatePublication", changes, disgo.Null)
+}
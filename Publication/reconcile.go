@@ -0,0 +1,51 @@
+package Publication
+
+import (
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"github.com/300brand/go-toml-config"
+	"github.com/300brand/logger"
+	"time"
+)
+
+var (
+	// How often every publication's counters are reconciled; 0 disables
+	cfgReconcileInterval = config.Duration("Publication.reconcile.interval", 24*time.Hour)
+	// Whether the periodic reconcile fixes counters or only reports them
+	cfgReconcileFix = config.Bool("Publication.reconcile.fix", false)
+)
+
+// Compares NumFeeds and NumArticles with the feeds and articles collections,
+// which drift as increments are missed or doubled, and optionally fixes them
+func (s *Service) Reconcile(in *types.PubReconcile, out *types.PubReconcileResults) (err error) {
+	counts := new(types.PubCounts)
+	if err = s.client.Call("StorageReader.PublicationCounts", &types.ObjectIds{in.Ids}, counts); err != nil {
+		return
+	}
+	out.Checked = len(counts.Counts)
+	for i := range counts.Counts {
+		c := &counts.Counts[i]
+		if c.NumFeeds == c.Feeds && c.NumArticles == c.Articles {
+			continue
+		}
+		out.Discrepancies = append(out.Discrepancies, *c)
+		logger.Debug.Printf("Publication.Reconcile: [P:%s] Feeds %d/%d; Articles %d/%d", c.Id.Hex(), c.NumFeeds, c.Feeds, c.NumArticles, c.Articles)
+		if !in.Fix {
+			continue
+		}
+		if err = s.client.Call("StorageWriter.PublicationCounts", c, disgo.Null); err != nil {
+			return
+		}
+		out.Fixed++
+	}
+	logger.Info.Printf("Publication.Reconcile: Checked %d publications, %d off, %d fixed", out.Checked, len(out.Discrepancies), out.Fixed)
+	return
+}
+
+func (s *Service) reconcileLoop() {
+	for _ = range time.Tick(*cfgReconcileInterval) {
+		if err := s.Reconcile(&types.PubReconcile{Fix: *cfgReconcileFix}, new(types.PubReconcileResults)); err != nil {
+			logger.Error.Printf("Publication.Reconcile: %s", err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package Publication
+
+import (
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"github.com/300brand/go-toml-config"
+	"github.com/300brand/logger"
+	"time"
+)
+
+// Removed publications may be restored until purged after this long
+var cfgRetention = config.Duration("Publication.retention", 90*24*time.Hour)
+
+// Hard-deletes publications removed longer ago than the retention period,
+// along with their feeds, articles and everything stored about the articles,
+// archived HTML included
+func (s *Service) Purge(in *disgo.NullType, out *types.PubPurge) (err error) {
+	thresh := &types.DateThreshold{time.Now().Add(-*cfgRetention)}
+	if err = s.client.Call("StorageWriter.PublicationPurge", thresh, out); err != nil {
+		return
+	}
+	logger.Info.Printf("Publication.Purge: Purged %d publications, %d feeds, %d articles removed before %s", out.Publications, out.Feeds, out.Articles, thresh.Threshold)
+	if len(out.ArchiveKeys) == 0 {
+		return
+	}
+	return s.client.Call("Article.ArchiveDelete", &types.ArchiveKeys{out.ArchiveKeys}, disgo.Null)
+}
+
+// Soft-deletes the publication and its feeds and drops its queued articles.
+// Removed publications are excluded from new searches.
+func (s *Service) Remove(in *types.ObjectId, out *types.PubRemoval) (err error) {
+	if err = s.client.Call("StorageWriter.PublicationRemove", in, out); err != nil {
+		return
+	}
+	logger.Info.Printf("Publication.Remove: [P:%s] Removed with %d feeds, %d queued articles", in.Id.Hex(), out.Feeds, out.QueuedArticles)
+	if out.Feeds == 0 {
+		return
+	}
+	return s.client.Call("StorageWriter.PubIncFeeds", &types.Inc{Id: in.Id, Delta: -out.Feeds}, disgo.Null)
+}
+
+// Brings back a removed publication and the feeds removed along with it
+func (s *Service) Restore(in *types.ObjectId, out *types.PubRemoval) (err error) {
+	if err = s.client.Call("StorageWriter.PublicationRestore", in, out); err != nil {
+		return
+	}
+	logger.Info.Printf("Publication.Restore: [P:%s] Restored with %d feeds", in.Id.Hex(), out.Feeds)
+	if out.Feeds == 0 {
+		return
+	}
+	return s.client.Call("StorageWriter.PubIncFeeds", &types.Inc{Id: in.Id, Delta: out.Feeds}, disgo.Null)
+}
@@ -0,0 +1,77 @@
+package Publication
+
+import (
+	"fmt"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"labix.org/v2/mgo/bson"
+	"strings"
+)
+
+// Creates or updates a named collection. Names are unique regardless of case
+// and every publication must exist.
+func (s *Service) SaveCollection(in *types.PubCollection, out *types.PubCollection) (err error) {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.Name == "" {
+		return fmt.Errorf("Collection name cannot be empty")
+	}
+	in.Key = strings.ToLower(in.Name)
+
+	existing := new(types.MultiPubCollections)
+	query := &types.MultiQuery{Query: bson.M{"key": in.Key}}
+	if err = s.client.Call("StorageReader.PubCollections", query, existing); err != nil {
+		return
+	}
+	for _, c := range existing.Collections {
+		if c.Id != in.Id {
+			return fmt.Errorf("Collection %q already exists", c.Name)
+		}
+	}
+
+	if len(in.PublicationIds) > 0 {
+		pubs := new(types.MultiPubs)
+		query := &types.MultiQuery{Query: bson.M{"_id": bson.M{"$in": in.PublicationIds}}}
+		if err = s.client.Call("StorageReader.Publications", query, pubs); err != nil {
+			return
+		}
+		found := make(map[bson.ObjectId]bool, len(pubs.Publications))
+		for _, p := range pubs.Publications {
+			found[p.ID] = true
+		}
+		for _, id := range in.PublicationIds {
+			if !found[id] {
+				return fmt.Errorf("Publication %s not found", id.Hex())
+			}
+		}
+	}
+	return s.client.Call("StorageWriter.PubCollection", in, out)
+}
+
+func (s *Service) Tag(in *types.PubTagChange, out *disgo.NullType) (err error) {
+	if in.Add, err = normalizeTags(in.Add); err != nil {
+		return
+	}
+	if in.Remove, err = normalizeTags(in.Remove); err != nil {
+		return
+	}
+	if len(in.Add) == 0 && len(in.Remove) == 0 {
+		return fmt.Errorf("No tags to add or remove")
+	}
+	return s.client.Call("StorageWriter.PublicationTags", in, out)
+}
+
+// Lowercases and trims tags, dropping duplicates
+func normalizeTags(in []string) (tags []string, err error) {
+	seen := make(map[string]bool, len(in))
+	for _, t := range in {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			return nil, fmt.Errorf("Tags cannot be empty")
+		}
+		if !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	return
+}
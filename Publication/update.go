@@ -0,0 +1,240 @@
+package Publication
+
+import (
+	"fmt"
+	"github.com/300brand/coverage/downloader"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Editable publication fields and how to apply each to the changes. Anything
+// else, counters and IDs included, is rejected.
+var updateFields = map[string]func(c *types.PubChanges, v interface{}) error{
+	"title": func(c *types.PubChanges, v interface{}) (err error) {
+		s, err := toString(v)
+		if err != nil {
+			return
+		}
+		if s == "" {
+			return fmt.Errorf("cannot be empty")
+		}
+		c.Title = &s
+		return
+	},
+	"url": func(c *types.PubChanges, v interface{}) (err error) {
+		s, err := toString(v)
+		if err != nil {
+			return
+		}
+		if err = validURL(s); err != nil {
+			return
+		}
+		c.URL = &s
+		return
+	},
+	"numreaders": func(c *types.PubChanges, v interface{}) (err error) {
+		n, err := toInt(v)
+		if err != nil {
+			return
+		}
+		if n < 0 {
+			return fmt.Errorf("cannot be negative")
+		}
+		c.NumReaders = &n
+		return
+	},
+	"xpaths.author": func(c *types.PubChanges, v interface{}) (err error) {
+		c.AuthorXPaths, err = toXPaths(v)
+		return
+	},
+	"xpaths.body": func(c *types.PubChanges, v interface{}) (err error) {
+		c.BodyXPaths, err = toXPaths(v)
+		return
+	},
+	"xpaths.date": func(c *types.PubChanges, v interface{}) (err error) {
+		c.DateXPaths, err = toXPaths(v)
+		return
+	},
+	"xpaths.title": func(c *types.PubChanges, v interface{}) (err error) {
+		c.TitleXPaths, err = toXPaths(v)
+		return
+	},
+	"settings.maxfilesize": func(c *types.PubChanges, v interface{}) (err error) {
+		n, err := toInt(v)
+		if err != nil {
+			return
+		}
+		if n < 0 {
+			return fmt.Errorf("cannot be negative")
+		}
+		if n > downloader.MaxFileSize {
+			return fmt.Errorf("cannot exceed the downloader limit of %d", downloader.MaxFileSize)
+		}
+		c.MaxFileSize = &n
+		return
+	},
+	"settings.nextpage": func(c *types.PubChanges, v interface{}) (err error) {
+		c.NextPageXPaths, err = toXPaths(v)
+		return
+	},
+}
+
+// Validates and saves changes to a publication. Nothing is saved unless every
+// field is accepted; the error names each rejected field. Once saved, a new
+// NumReaders is recorded in the readership history as effective now.
+func (s *Service) Update(in *types.PubUpdate, out *disgo.NullType) (err error) {
+	changes, err := pubChanges(in)
+	if err != nil {
+		return
+	}
+	if err = s.client.Call("StorageWriter.UpdatePublication", changes, out); err != nil {
+		return
+	}
+	if changes.NumReaders != nil {
+		u := &types.ReadershipUpdate{PublicationId: in.Id, Readers: *changes.NumReaders}
+		err = s.saveReadership(u, time.Now())
+	}
+	return
+}
+
+func pubChanges(in *types.PubUpdate) (changes *types.PubChanges, err error) {
+	if !in.Id.Valid() {
+		return nil, fmt.Errorf("Invalid publication Id")
+	}
+	if len(in.Fields) == 0 {
+		return nil, fmt.Errorf("No fields to update")
+	}
+
+	keys := make([]string, 0, len(in.Fields))
+	for k := range in.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	changes = &types.PubChanges{Id: in.Id}
+	var rejected []string
+	for _, k := range keys {
+		apply, ok := updateFields[strings.ToLower(k)]
+		if !ok {
+			rejected = append(rejected, fmt.Sprintf("%s: not an editable field", k))
+			continue
+		}
+		if err := apply(changes, in.Fields[k]); err != nil {
+			rejected = append(rejected, fmt.Sprintf("%s: %s", k, err))
+		}
+	}
+	if len(rejected) > 0 {
+		return nil, fmt.Errorf("Rejected fields: %s", strings.Join(rejected, "; "))
+	}
+	return
+}
+
+func toString(v interface{}) (s string, err error) {
+	s, ok := v.(string)
+	if !ok {
+		err = fmt.Errorf("expected a string, got %T", v)
+	}
+	return strings.TrimSpace(s), err
+}
+
+// JSON numbers arrive as float64, RPC numbers as any integer type
+func toInt(v interface{}) (n int64, err error) {
+	switch t := v.(type) {
+	case int:
+		return int64(t), nil
+	case int32:
+		return int64(t), nil
+	case int64:
+		return t, nil
+	case float64:
+		if t != math.Trunc(t) || math.Abs(t) > math.MaxInt64 {
+			return 0, fmt.Errorf("expected an integer, got %v", t)
+		}
+		return int64(t), nil
+	}
+	return 0, fmt.Errorf("expected an integer, got %T", v)
+}
+
+func toXPaths(v interface{}) (xpaths *[]string, err error) {
+	var list []string
+	switch t := v.(type) {
+	case []string:
+		list = t
+	case []interface{}:
+		list = make([]string, len(t))
+		for i := range t {
+			s, ok := t[i].(string)
+			if !ok {
+				return nil, fmt.Errorf("XPath %d: expected a string, got %T", i, t[i])
+			}
+			list[i] = s
+		}
+	default:
+		return nil, fmt.Errorf("expected a list of XPaths, got %T", v)
+	}
+	for i, xpath := range list {
+		if err = validXPath(xpath); err != nil {
+			return nil, fmt.Errorf("XPath %d %q: %s", i, xpath, err)
+		}
+	}
+	return &list, nil
+}
+
+func validURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("must be an http or https URL")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("missing host")
+	}
+	return nil
+}
+
+// Catches the usual typos: unbalanced brackets and quotes, and relative paths
+// which would never match from the document root
+func validXPath(xpath string) error {
+	if strings.TrimSpace(xpath) == "" {
+		return fmt.Errorf("empty")
+	}
+	if c := strings.TrimSpace(xpath)[0]; c != '/' && c != '(' {
+		return fmt.Errorf("must start with / or (")
+	}
+	var (
+		stack []rune
+		quote rune
+	)
+	pairs := map[rune]rune{')': '(', ']': '['}
+	for _, r := range xpath {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(' || r == '[':
+			stack = append(stack, r)
+		case r == ')' || r == ']':
+			if len(stack) == 0 || stack[len(stack)-1] != pairs[r] {
+				return fmt.Errorf("unexpected %c", r)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if quote != 0 {
+		return fmt.Errorf("unterminated string")
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("unclosed %c", stack[len(stack)-1])
+	}
+	return nil
+}
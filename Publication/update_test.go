@@ -0,0 +1,82 @@
+package Publication
+
+import (
+	"github.com/300brand/coverageservices/types"
+	"labix.org/v2/mgo/bson"
+	"strings"
+	"testing"
+)
+
+func TestPubChanges(t *testing.T) {
+	in := &types.PubUpdate{
+		Id: bson.NewObjectId(),
+		Fields: map[string]interface{}{
+			"title":                " Example News ",
+			"url":                  "http://example.com/",
+			"numreaders":           float64(1500),
+			"xpaths.body":          []interface{}{`//div[@id="story"]`, `(//article)[1]`},
+			"settings.maxfilesize": float64(512 << 10),
+			"settings.nextpage":    []interface{}{`//a[@rel="next"]/@href`},
+		},
+	}
+	changes, err := pubChanges(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *changes.Title != "Example News" {
+		t.Errorf("Title: %q", *changes.Title)
+	}
+	if *changes.NumReaders != 1500 {
+		t.Errorf("NumReaders: %d", *changes.NumReaders)
+	}
+	if len(*changes.BodyXPaths) != 2 {
+		t.Errorf("BodyXPaths: %q", *changes.BodyXPaths)
+	}
+	if *changes.MaxFileSize != 512<<10 || len(*changes.NextPageXPaths) != 1 {
+		t.Errorf("Settings: %d %q", *changes.MaxFileSize, *changes.NextPageXPaths)
+	}
+	if changes.AuthorXPaths != nil || changes.DateXPaths != nil {
+		t.Error("Untouched XPaths should be nil")
+	}
+}
+
+func TestPubChangesRejected(t *testing.T) {
+	in := &types.PubUpdate{
+		Id: bson.NewObjectId(),
+		Fields: map[string]interface{}{
+			"numarticles":          float64(0),
+			"numreaders":           1.5,
+			"title":                42,
+			"url":                  "ftp://example.com",
+			"xpaths.title":         []interface{}{`//h1[@class="title"`},
+			"settings.maxfilesize": float64(-1),
+		},
+	}
+	_, err := pubChanges(in)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, field := range []string{"numarticles:", "numreaders:", "title:", "url:", "xpaths.title:", "settings.maxfilesize:"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Expected %s to be rejected: %s", field, err)
+		}
+	}
+}
+
+func TestValidXPath(t *testing.T) {
+	for xpath, valid := range map[string]bool{
+		`//div[@class="body"]//p`:   true,
+		`(//h1)[1]`:                 true,
+		`//a[contains(., "Next")]`:  true,
+		`//a[contains(., "Next]")]`: true,
+		`div/p`:                     false,
+		`//div[@class="body"`:       false,
+		`//div[@class="body]`:       false,
+		`//div)`:                    false,
+		``:                          false,
+	} {
+		if err := validXPath(xpath); (err == nil) != valid {
+			t.Errorf("%q: expected valid=%t, got %v", xpath, valid, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package Publication
+
+import (
+	"fmt"
+	"github.com/300brand/coverage"
+	"github.com/300brand/coverage/downloader"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/coverageservices/xpaths"
+	"github.com/300brand/go-toml-config"
+	"labix.org/v2/mgo/bson"
+)
+
+var (
+	cfgXPathSamples    = config.Int("Publication.xpaths.samples", 10)
+	cfgXPathMaxSamples = config.Int("Publication.xpaths.maxsamples", 50)
+)
+
+// Proposes Author, Body, Date and Title XPaths from sample articles, with the
+// share of samples each one matched. Nothing is saved; apply the chosen
+// XPaths with Publication.Update.
+func (s *Service) SuggestXPaths(in *types.XPathSample, out *types.XPathSuggestions) (err error) {
+	var pages [][]byte
+	if len(in.URLs) > 0 {
+		if len(in.URLs) > *cfgXPathMaxSamples {
+			return fmt.Errorf("Too many URLs; maximum is %d", *cfgXPathMaxSamples)
+		}
+		for _, u := range in.URLs {
+			if err := validURL(u); err != nil {
+				out.Errors = append(out.Errors, fmt.Sprintf("%s: %s", u, err))
+				continue
+			}
+			a := &coverage.Article{URL: u}
+			if err := downloader.Article(a); err != nil {
+				out.Errors = append(out.Errors, fmt.Sprintf("%s: %s", u, err))
+				continue
+			}
+			pages = append(pages, a.Text.HTML)
+		}
+	} else {
+		if pages, err = s.recentHTML(in.PublicationId, in.Samples); err != nil {
+			return
+		}
+	}
+	if len(pages) == 0 {
+		return fmt.Errorf("No sample pages to analyze")
+	}
+
+	suggestions := xpaths.Suggest(pages)
+	out.Samples = suggestions.Samples
+	out.Author = candidates(suggestions.Author)
+	out.Body = candidates(suggestions.Body)
+	out.Date = candidates(suggestions.Date)
+	out.Title = candidates(suggestions.Title)
+	return
+}
+
+// HTML of the publication's latest articles, from the archive when possible
+// and the stored copy otherwise
+func (s *Service) recentHTML(pubId bson.ObjectId, n int) (pages [][]byte, err error) {
+	if n <= 0 {
+		n = *cfgXPathSamples
+	}
+	if n > *cfgXPathMaxSamples {
+		n = *cfgXPathMaxSamples
+	}
+	query := &types.MultiQuery{
+		Query:  bson.M{"publicationid": pubId},
+		Select: bson.M{"text.html": 1},
+		Sort:   "-published",
+		Limit:  n,
+	}
+	articles := new(types.MultiArticles)
+	if err = s.client.Call("StorageReader.Articles", query, articles); err != nil {
+		return
+	}
+	for _, a := range articles.Articles {
+		archived := new(types.ArchivedHTML)
+		if err := s.client.Call("Article.Archived", &types.ObjectId{a.ID}, archived); err == nil && len(archived.HTML) > 0 {
+			pages = append(pages, archived.HTML)
+			continue
+		}
+		if len(a.Text.HTML) > 0 {
+			pages = append(pages, a.Text.HTML)
+		}
+	}
+	return
+}
+
+func candidates(in []xpaths.Candidate) (out []types.XPathCandidate) {
+	out = make([]types.XPathCandidate, len(in))
+	for i, c := range in {
+		out[i] = types.XPathCandidate{XPath: c.XPath, Hits: c.Hits, Rate: c.Rate}
+	}
+	return
+}
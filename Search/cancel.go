@@ -0,0 +1,85 @@
+package Search
+
+import (
+	"fmt"
+	"github.com/300brand/coverage"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"github.com/300brand/logger"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"time"
+)
+
+// Stops a running search or group search once the chunk of dates being
+// searched is done. The request is stored with the search, so it reaches
+// whichever instance runs it. The search is marked cancelled, its partial
+// results are dropped and no notifications are sent
+func (s *Service) Cancel(in *types.ObjectId, out *disgo.NullType) (err error) {
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		return
+	}
+	defer session.Close()
+
+	running := bson.M{"_id": in.Id, "state": stateRunning}
+	err = session.DB("300brand_Search").C("Search").Update(running, bson.M{"$set": bson.M{"cancelrequested": time.Now()}})
+	if err == mgo.ErrNotFound {
+		err = s.cancelGroup(in.Id)
+	}
+	if err != nil {
+		return
+	}
+	logger.Info.Printf("Search.Cancel: [%s] Cancel requested", in.Id.Hex())
+	return
+}
+
+// Group searches are stored with the rest of the coverage data. Their
+// searches check the group's flag along with their own.
+func (s *Service) cancelGroup(id bson.ObjectId) (err error) {
+	gs := new(coverage.GroupSearch)
+	if err = s.client.Call("StorageReader.GroupSearch", &types.ObjectId{id}, gs); err != nil || gs.Complete != nil {
+		return fmt.Errorf("Search %s is not running", id.Hex())
+	}
+	return s.client.Call("StorageWriter.GroupSearchCancel", &types.ObjectId{id}, disgo.Null)
+}
+
+// Reports whether cancelling the search, or the group it belongs to, has
+// been requested
+func (s *Service) cancelRequested(session *mgo.Session, id, groupId bson.ObjectId) bool {
+	query := bson.M{"_id": id, "cancelrequested": bson.M{"$exists": true}}
+	n, err := session.DB("300brand_Search").C("Search").Find(query).Count()
+	if err != nil {
+		logger.Error.Printf("Error checking search %s for cancellation: %s", id.Hex(), err)
+		return false
+	}
+	return n > 0 || (groupId != "" && s.groupCancelled(groupId))
+}
+
+func (s *Service) groupCancelled(id bson.ObjectId) bool {
+	state := new(types.GroupSearchCancel)
+	if err := s.client.Call("StorageReader.GroupSearchCancel", &types.ObjectId{id}, state); err != nil {
+		logger.Error.Printf("Error checking group search %s for cancellation: %s", id.Hex(), err)
+		return false
+	}
+	return !state.Cancelled.IsZero()
+}
+
+// Marks the search cancelled and drops its results collection
+func (s *Service) abandon(id bson.ObjectId) {
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		logger.Error.Printf("Error connecting to MongoDB: %s", err)
+		return
+	}
+	defer session.Close()
+
+	db := session.DB("300brand_Search")
+	if err := db.C("Search").UpdateId(id, bson.M{"$set": bson.M{"cancelled": time.Now(), "state": stateCancelled}}); err != nil {
+		logger.Error.Printf("Error marking search %s cancelled: %s", id.Hex(), err)
+	}
+	// Nothing to drop when SearchInto hasn't written any results yet
+	if err := db.C("Results_" + id.Hex()).DropCollection(); err != nil && err.Error() != "ns not found" {
+		logger.Error.Printf("Error dropping Results_%s: %s", id.Hex(), err)
+	}
+}
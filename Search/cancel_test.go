@@ -0,0 +1,81 @@
+package Search
+
+import (
+	"github.com/300brand/coverageservices/types"
+	"labix.org/v2/mgo/bson"
+	"testing"
+	"time"
+)
+
+func TestSearchChunksCancelled(t *testing.T) {
+	in := dateQuery(time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2013, 12, 31, 0, 0, 0, 0, time.UTC))
+	id := bson.NewObjectId()
+	var searched int
+	into := func(query string, into bson.ObjectId) error {
+		searched++
+		return nil
+	}
+
+	if err := searchChunks(nil, into, in, `"a"`, nil, id, func() bool { return true }); err != errCancelled {
+		t.Errorf("Expected errCancelled before the first chunk, got %v", err)
+	}
+	if searched != 0 {
+		t.Errorf("Searched %d chunks after cancelling", searched)
+	}
+
+	// Cancel requested while the first chunk runs
+	checks := 0
+	cancelled := func() bool {
+		checks++
+		return checks > 1
+	}
+	if err := searchChunks(nil, into, in, `"a"`, nil, id, cancelled); err != errCancelled {
+		t.Errorf("Expected errCancelled after the first chunk, got %v", err)
+	}
+	if searched != 1 {
+		t.Errorf("Expected 1 chunk searched, got %d", searched)
+	}
+}
+
+func TestDateChunks(t *testing.T) {
+	start := time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		Days, Span, Chunks int
+	}{
+		{30, 0, 1},
+		{30, 29, 1},
+		{30, 30, 2},
+		{30, 365, 13},
+		{7, 6, 1},
+		{1, 3, 4},
+		{0, 3650, 1},
+	} {
+		end := start.AddDate(0, 0, test.Span)
+		_, total := mongoQuery(dateQuery(start, end), `"a"`, nil)
+
+		chunks := dateChunks(start, end, test.Days)
+		if len(chunks) != test.Chunks {
+			t.Errorf("%d days over %d: Expected %d chunks, got %d", test.Days, test.Span, test.Chunks, len(chunks))
+		}
+		sum := 0
+		for i, c := range chunks {
+			_, n := mongoQuery(dateQuery(c.Start, c.End), `"a"`, nil)
+			if test.Days > 0 && n > test.Days {
+				t.Errorf("%d days over %d: Chunk %d covers %d dates", test.Days, test.Span, i, n)
+			}
+			if i > 0 && !c.End.Before(chunks[i-1].Start) {
+				t.Errorf("%d days over %d: Chunk %d overlaps the previous", test.Days, test.Span, i)
+			}
+			sum += n
+		}
+		if sum != total {
+			t.Errorf("%d days over %d: Chunks cover %d dates, expected %d", test.Days, test.Span, sum, total)
+		}
+	}
+}
+
+func dateQuery(start, end time.Time) *types.SearchQuery {
+	q := new(types.SearchQuery)
+	q.Dates.Start, q.Dates.End = start, end
+	return q
+}
@@ -0,0 +1,100 @@
+package Search
+
+import (
+	"errors"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/go-toml-config"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"time"
+)
+
+// Publish dates covered by each SearchInto call. A cancelled search stops
+// once the chunk in progress is done; 0 searches the whole range at once
+var cfgChunkDays = config.Int("Search.chunkdays", 30)
+
+var errCancelled = errors.New("Search cancelled")
+
+type dateRange struct {
+	Start, End time.Time
+}
+
+// Runs the search one chunk of publish dates at a time, newest first. The
+// first chunk goes straight into Results_<id>; later chunks land in their
+// own collection and are merged in, so the outcome does not depend on
+// whether SearchInto replaces or appends to its target.
+func searchChunks(session *mgo.Session, into func(string, bson.ObjectId) error, in *types.SearchQuery, queryIn string, exclude []bson.ObjectId, id bson.ObjectId, cancelled func() bool) (err error) {
+	for i, dates := range dateChunks(in.Dates.Start, in.Dates.End, *cfgChunkDays) {
+		if cancelled() {
+			return errCancelled
+		}
+
+		chunk := *in
+		chunk.Dates.Start, chunk.Dates.End = dates.Start, dates.End
+		query, _ := mongoQuery(&chunk, queryIn, exclude)
+		if i == 0 {
+			if err = into(query, id); err != nil {
+				return
+			}
+			continue
+		}
+
+		db := session.DB("300brand_Search")
+		tmpId := bson.NewObjectId()
+		tmp := db.C("Results_" + tmpId.Hex())
+		if err = into(query, tmpId); err == nil {
+			err = mergeResults(tmp, db.C("Results_"+id.Hex()))
+		}
+		if derr := tmp.DropCollection(); derr != nil && derr.Error() != "ns not found" && err == nil {
+			err = derr
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func mergeResults(from, to *mgo.Collection) (err error) {
+	batch := make([]interface{}, 0, resultBatch)
+	iter := from.Find(nil).Iter()
+	doc := bson.M{}
+	for iter.Next(&doc) {
+		batch = append(batch, doc)
+		doc = bson.M{}
+		if len(batch) == resultBatch {
+			if err = to.Insert(batch...); err != nil {
+				iter.Close()
+				return
+			}
+			batch = batch[:0]
+		}
+	}
+	if err = iter.Close(); err != nil {
+		return
+	}
+	if len(batch) > 0 {
+		err = to.Insert(batch...)
+	}
+	return
+}
+
+// Splits start..end (inclusive, by day) into ranges of at most days, newest
+// first
+func dateChunks(start, end time.Time, days int) (chunks []dateRange) {
+	if days <= 0 {
+		return []dateRange{{start, end}}
+	}
+	for !end.Before(start) {
+		from := end.AddDate(0, 0, 1-days)
+		if from.Before(start) {
+			from = start
+		}
+		chunks = append(chunks, dateRange{from, end})
+		end = from.AddDate(0, 0, -1)
+	}
+	if len(chunks) == 0 {
+		chunks = []dateRange{{start, end}}
+	}
+	return
+}
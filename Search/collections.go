@@ -0,0 +1,126 @@
+package Search
+
+import (
+	"fmt"
+	"github.com/300brand/coverageservices/types"
+	"labix.org/v2/mgo/bson"
+	"strings"
+)
+
+// Expands collection names and tags into publication IDs. A nil include list
+// means every publication, in which case exclude also covers publications
+// removed through Publication.Remove. Otherwise exclusions and removed
+// publications are taken out of include and exclude is empty.
+func (s *Service) resolvePublications(in *types.SearchQuery) (include, exclude []bson.ObjectId, err error) {
+	excludeCollections, err := s.collectionPubs(in.ExcludeCollections)
+	if err != nil {
+		return
+	}
+	excludeTagged, err := s.taggedPubs(in.ExcludeTags)
+	if err != nil {
+		return
+	}
+	excluded := make(map[bson.ObjectId]bool)
+	exclude = appendUnique(exclude, excluded, excludeCollections, excludeTagged)
+
+	if len(in.PublicationIds) == 0 && len(in.Collections) == 0 && len(in.Tags) == 0 {
+		deleted, err := s.deletedPublications(nil)
+		return nil, appendUnique(exclude, excluded, deleted), err
+	}
+	collections, err := s.collectionPubs(in.Collections)
+	if err != nil {
+		return
+	}
+	tagged, err := s.taggedPubs(in.Tags)
+	if err != nil {
+		return
+	}
+	// Seeding with the exclusions keeps them out of include
+	exclude = nil
+	if include = appendUnique(nil, excluded, in.PublicationIds, collections, tagged); len(include) > 0 {
+		var deleted []bson.ObjectId
+		if deleted, err = s.deletedPublications(include); err != nil {
+			return
+		}
+		include = withoutIds(include, deleted)
+	}
+	if len(include) == 0 {
+		err = fmt.Errorf("No publications match the requested publications, collections and tags")
+	}
+	return
+}
+
+// Publication IDs of the named collections; unknown names are an error
+func (s *Service) collectionPubs(names []string) (ids []bson.ObjectId, err error) {
+	if len(names) == 0 {
+		return
+	}
+	keys := normalizeNames(names)
+	collections := new(types.MultiPubCollections)
+	query := &types.MultiQuery{Query: bson.M{"key": bson.M{"$in": keys}}}
+	if err = s.client.Call("StorageReader.PubCollections", query, collections); err != nil {
+		return
+	}
+	found := make(map[string]bool, len(collections.Collections))
+	for _, c := range collections.Collections {
+		found[c.Key] = true
+		ids = append(ids, c.PublicationIds...)
+	}
+	for i, key := range keys {
+		if !found[key] {
+			return nil, fmt.Errorf("Unknown publication collection %q", names[i])
+		}
+	}
+	return
+}
+
+// IDs of publications with any of the tags
+func (s *Service) taggedPubs(tags []string) (ids []bson.ObjectId, err error) {
+	if len(tags) == 0 {
+		return
+	}
+	pubs := new(types.MultiPubs)
+	query := &types.MultiQuery{Query: bson.M{"tags": bson.M{"$in": normalizeNames(tags)}}}
+	if err = s.client.Call("StorageReader.Publications", query, pubs); err != nil {
+		return
+	}
+	for _, p := range pubs.Publications {
+		ids = append(ids, p.ID)
+	}
+	return
+}
+
+// Collection keys and tags are stored lowercase
+func normalizeNames(names []string) (keys []string) {
+	keys = make([]string, len(names))
+	for i := range names {
+		keys[i] = strings.ToLower(strings.TrimSpace(names[i]))
+	}
+	return
+}
+
+func appendUnique(dst []bson.ObjectId, seen map[bson.ObjectId]bool, lists ...[]bson.ObjectId) []bson.ObjectId {
+	for _, ids := range lists {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				dst = append(dst, id)
+			}
+		}
+	}
+	return dst
+}
+
+func withoutIds(ids, remove []bson.ObjectId) []bson.ObjectId {
+	drop := make(map[bson.ObjectId]bool, len(remove))
+	for _, id := range remove {
+		drop[id] = true
+	}
+	kept := ids[:0]
+	for _, id := range ids {
+		if !drop[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,35 @@
+package Search
+
+import (
+	"fmt"
+	"github.com/300brand/coverageservices/entity"
+	"github.com/300brand/coverageservices/types"
+	"strings"
+)
+
+// Replaces the entity names of the query, which may be aliases, with the keys
+// Article.Process stores on articles. Unknown names are an error since no
+// article could mention them.
+func (s *Service) resolveEntities(in *types.SearchQuery) (err error) {
+	if len(in.Entities) == 0 {
+		return
+	}
+	list := new(types.MultiEntities)
+	if err = s.client.Call("StorageReader.Entities", &types.MultiQuery{}, list); err != nil {
+		return
+	}
+	g := entity.NewGazetteer()
+	for _, e := range list.Entities {
+		g.Add(e.Name, e.Type, e.Aliases, e.CaseSensitive)
+	}
+	keys := make([]string, len(in.Entities))
+	for i, name := range in.Entities {
+		canonical, ok := g.Canonical(name)
+		if !ok {
+			return fmt.Errorf("Unknown entity %q", name)
+		}
+		keys[i] = strings.ToLower(canonical)
+	}
+	in.Entities = keys
+	return
+}
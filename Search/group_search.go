@@ -3,6 +3,7 @@ package Search
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"github.com/300brand/coverage"
 	"github.com/300brand/coverageservices/types"
 	"github.com/300brand/disgo"
@@ -15,14 +16,27 @@ import (
 func (s *Service) GroupSearch(in *types.GroupQuery, out *types.SearchQueryResponse) (err error) {
 	var gsLock sync.Mutex
 
+	// Sub-searches run in the background, so reject malformed queries now
+	for i, q := range in.Queries {
+		if _, qerr := parseQuery(queryV1toV2(q.Q)); qerr != nil {
+			return fmt.Errorf("Invalid query %d at offset %d: %s", i, qerr.Offset, qerr.Message)
+		}
+	}
+
 	gs := coverage.NewGroupSearch()
 	gs.Notify.Done = in.Notify.Done
 	s.client.Call("StorageWriter.NewGroupSearch", gs, gs)
 
 	searchQuery := types.SearchQuery{
-		Dates:          in.Dates,
-		PublicationIds: in.PublicationIds,
-		Foreground:     true,
+		Dates:              in.Dates,
+		PublicationIds:     in.PublicationIds,
+		Collections:        in.Collections,
+		Tags:               in.Tags,
+		ExcludeCollections: in.ExcludeCollections,
+		ExcludeTags:        in.ExcludeTags,
+		Entities:           in.Entities,
+		Foreground:         true,
+		GroupId:            gs.Id,
 	}
 	// Do not want the complete notification to send out after each sub-search
 	searchQuery.Notify.Social = in.Notify.Social
@@ -47,6 +61,11 @@ func (s *Service) GroupSearch(in *types.GroupQuery, out *types.SearchQueryRespon
 	// notification of completeness
 	go func(gs *coverage.GroupSearch) {
 		wg.Wait()
+		// Search.Cancel already marked the group cancelled
+		if s.groupCancelled(gs.Id) {
+			logger.Info.Printf("Group Search %s cancelled after %s", gs.Id.Hex(), time.Since(gs.Start))
+			return
+		}
 		// This is a little manual, but it's explicit
 		gs.Complete = new(time.Time)
 		*gs.Complete = time.Now()
@@ -81,9 +100,20 @@ func (s *Service) GroupSearchNotifyComplete(in *types.ObjectId, out *disgo.NullT
 		}
 	}
 
+	// Include the combined sentiment of all sub-searches
+	payload := struct {
+		*coverage.GroupSearch
+		Sentiment types.SentimentSummary
+	}{GroupSearch: info}
+	sentiment := new(types.GroupSentiment)
+	if err := s.GroupSentiment(in, sentiment); err != nil {
+		logger.Warn.Printf("Search.GroupSearchNotifyComplete: [%s] Sentiment: %s", in.Id.Hex(), err)
+	}
+	payload.Sentiment = sentiment.Overall
+
 	buf := new(bytes.Buffer)
 	enc := json.NewEncoder(buf)
-	if err = enc.Encode(info); err != nil {
+	if err = enc.Encode(payload); err != nil {
 		return
 	}
 
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"github.com/300brand/coverage"
 	"github.com/300brand/coverage/article/lexer"
-	"github.com/300brand/coverage/social"
 	"github.com/300brand/coverageservices/service"
 	"github.com/300brand/coverageservices/types"
 	"github.com/300brand/disgo"
@@ -16,7 +15,6 @@ import (
 	"labix.org/v2/mgo"
 	"labix.org/v2/mgo/bson"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -27,7 +25,8 @@ type Service struct {
 var (
 	_ service.Service = &Service{}
 
-	cfgSocialDelay = config.Duration("Search.socialdelay", time.Second)
+	// Minimum time between articles for each Search.Social worker
+	cfgSocialDelay = config.Duration("Search.socialdelay", 250*time.Millisecond)
 	cfgMongoServer = config.String("Search.mongodb", "127.0.0.1:27017")
 )
 
@@ -39,6 +38,7 @@ func init() {
 
 func (s *Service) Start(client *disgo.Client) (err error) {
 	s.client = client
+	s.interruptRuns()
 	return
 }
 
@@ -50,9 +50,18 @@ func (s *Service) SearchNotifyComplete(in *types.ObjectId, out *disgo.NullType)
 		return
 	}
 
+	// Sentiment rides along with the search information
+	payload := struct {
+		*coverage.Search
+		Sentiment types.SentimentSummary
+	}{Search: info}
+	if payload.Sentiment, err = s.sentimentSummary(in.Id); err != nil {
+		logger.Warn.Printf("Search.SearchNotifyComplete: [%s] Sentiment: %s", in.Id.Hex(), err)
+	}
+
 	buf := new(bytes.Buffer)
 	enc := json.NewEncoder(buf)
-	if err = enc.Encode(info); err != nil {
+	if err = enc.Encode(payload); err != nil {
 		return
 	}
 
@@ -69,6 +78,25 @@ func (s *Service) Search(in *types.SearchQuery, out *types.SearchQueryResponse)
 	id := bson.NewObjectId()
 	start := time.Now()
 
+	include, exclude, err := s.resolvePublications(in)
+	if err != nil {
+		return
+	}
+	in.PublicationIds = include
+	if err = s.resolveEntities(in); err != nil {
+		return
+	}
+
+	// Reject malformed queries before the search is accepted
+	queryIn, err := queryV2(in)
+	if err != nil {
+		return
+	}
+	if _, qerr := parseQuery(queryIn); qerr != nil {
+		return fmt.Errorf("Invalid query at offset %d: %s", qerr.Offset, qerr.Message)
+	}
+	query, buckets := mongoQuery(in, queryIn, exclude)
+
 	{ // Fill in legacy search document for export later (TODO Remove later?)
 		cs := coverage.NewSearch()
 		cs.Id = id
@@ -80,6 +108,7 @@ func (s *Service) Search(in *types.SearchQuery, out *types.SearchQueryResponse)
 		if err = s.client.Call("StorageWriter.NewSearch", cs, cs); err != nil {
 			return
 		}
+		s.setState(id, stateRunning)
 	}
 
 	search, err := mongosearch.New(*cfgMongoServer, "300brand_Articles.Articles", "300brand_Search.Results")
@@ -92,70 +121,48 @@ func (s *Service) Search(in *types.SearchQuery, out *types.SearchQueryResponse)
 		out = lexer.Keywords([]byte(s))
 		return
 	}
+	// Entity keys are already lowercase and may hold several words
+	entityFunc := func(s string) (out interface{}, isArray bool, err error) {
+		return s, false, nil
+	}
 	search.SetCaseSensitive(in.CaseSensitive)
 	search.SetAll("text.words.all")
 	search.SetKeyword("text.words.keywords", keywordFunc, "keywords")
 	search.SetPubdate("pubdate.date", mongosearch.ConvertDateInt, "published")
 	search.SetPubid("publicationid", mongosearch.ConvertBsonId, "publicationid")
+	search.SetKeyword("entities.key", entityFunc, "entities")
 
-	// This is just silly, but most efficient way to calculate
-	dates := []time.Time{}
-	for st, t := in.Dates.Start.AddDate(0, 0, -1), in.Dates.End; t.After(st); t = t.AddDate(0, 0, -1) {
-		dates = append(dates, t)
-	}
-	// Cast dates to string and proper format
-	queryDates := make([]string, len(dates))
-	for i := range dates {
-		queryDates[i] = fmt.Sprintf("'%s'", dates[i].Format(mongosearch.TimeLayout))
-	}
-
-	queryIn := ""
-	switch in.Version {
-	case 0, 1:
-		queryIn = queryV1toV2(in.Q)
-	case 2:
-		// Can't decide if the date range should be expected in the input?
-		queryIn = in.Q
-	default:
-		return fmt.Errorf("Invalid version: %d", in.Version)
-	}
-
-	query := fmt.Sprintf(
-		"published:(%s) AND keywords:(%s)",
-		strings.Join(queryDates, " OR "),
-		queryIn,
-	)
-
-	if len(in.PublicationIds) > 0 {
-		ids := make([]string, len(in.PublicationIds))
-		for i, id := range in.PublicationIds {
-			ids[i] = id.Hex()
-		}
-		query += fmt.Sprintf(" AND publicationid:(%s)", strings.Join(ids, " OR "))
-	}
-
-	logger.Warn.Printf("Search.Search: Sending %s", query)
+	logger.Warn.Printf("Search.Search: Sending %s (%d dates)", query, buckets)
 
 	doSearch := func() {
-		if err := search.SearchInto(query, id); err != nil {
-			logger.Error.Printf("SearchInto: [%s] [%s] - %s", id.Hex(), query, err)
-			return
-		}
-
 		{ // Update search completion; transfer IDs (TODO Remove later?)
 			session, err := mgo.Dial(*cfgMongoServer)
 			if err != nil {
 				logger.Error.Printf("Error connecting to MongoDB: %s", err)
+				s.fail(id, err)
 				return
 			}
 			defer session.Close()
 
+			cancelled := func() bool { return s.cancelRequested(session, id, in.GroupId) }
+			switch err := searchChunks(session, search.SearchInto, in, queryIn, exclude, id, cancelled); err {
+			case nil:
+			case errCancelled:
+				s.abandon(id)
+				return
+			default:
+				logger.Error.Printf("SearchInto: [%s] [%s] - %s", id.Hex(), query, err)
+				s.fail(id, err)
+				return
+			}
+
 			ids := []struct {
 				Id bson.ObjectId `bson:"_id"`
 			}{}
 			db := session.DB("300brand_Search")
 			if err := db.C("Results_" + id.Hex()).Find(nil).Select(bson.M{"_id": 1}).All(&ids); err != nil {
 				logger.Error.Printf("Error retrieving all IDs from Results_%s: %s", id.Hex(), err)
+				s.fail(id, err)
 				return
 			}
 
@@ -163,14 +170,35 @@ func (s *Service) Search(in *types.SearchQuery, out *types.SearchQueryResponse)
 			for i := range ids {
 				articleids[i] = ids[i].Id
 			}
-			if err := db.C("Search").UpdateId(id, bson.M{
-				"$set": bson.M{
-					"completed": time.Now(),
-					"articles":  articleids,
-					"results":   len(articleids),
-				},
-			}); err != nil {
+			terms := queryTerms(queryIn)
+			set := bson.M{
+				"completed": time.Now(),
+				"state":     stateCompleted,
+				"articles":  articleids,
+				"results":   len(articleids),
+				"terms":     terms,
+			}
+			if reach, err := s.searchReach(session, id); err != nil {
+				logger.Error.Printf("Error calculating reach for Results_%s: %s", id.Hex(), err)
+			} else {
+				set["reach"] = reach
+			}
+			if *cfgSentiment {
+				summary, err := scoreResults(session, id, terms)
+				if err != nil {
+					logger.Error.Printf("Error scoring sentiment for Results_%s: %s", id.Hex(), err)
+					s.fail(id, err)
+					return
+				}
+				set["sentiment"] = summary
+			}
+			if cancelled() {
+				s.abandon(id)
+				return
+			}
+			if err := db.C("Search").UpdateId(id, bson.M{"$set": set}); err != nil {
 				logger.Error.Printf("Error updating search record [%s]: %s", id.Hex(), err)
+				s.fail(id, err)
 				return
 			}
 		}
@@ -200,53 +228,3 @@ func (s *Service) Search(in *types.SearchQuery, out *types.SearchQueryResponse)
 
 	return
 }
-
-func (s *Service) Social(in *types.ObjectId, out *disgo.NullType) (err error) {
-	info := &coverage.Search{}
-	if err = s.client.Call("StorageReader.Search", in, info); err != nil {
-		return
-	}
-
-	go func(info coverage.Search) {
-		for _, id := range info.Articles {
-			go func(id bson.ObjectId) {
-				// Get article from DB
-				logger.Debug.Printf("Getting %s from DB", id.Hex())
-				a := &coverage.Article{}
-				if err := s.client.Call("StorageReader.Article", types.ObjectId{id}, a); err != nil {
-					logger.Error.Print(err)
-					return
-				}
-				// Get stats
-				logger.Debug.Printf("Calling Social.Article for %s", id.Hex())
-				var socialStats social.Stats
-				if err := s.client.Call("Social.Article", a, &socialStats); err != nil {
-					logger.Error.Print(err)
-					return
-				}
-				// Send stats to frontend
-				stats := struct {
-					ArticleId, SearchId bson.ObjectId
-					Stats               social.Stats
-				}{id, info.Id, socialStats}
-
-				buf := new(bytes.Buffer)
-				enc := json.NewEncoder(buf)
-				if err = enc.Encode(stats); err != nil {
-					return
-				}
-				logger.Debug.Printf("Sending %+v to %s", stats, info.Notify.Social)
-
-				resp, err := http.Post(info.Notify.Social, "application/json", buf)
-				if err != nil {
-					logger.Error.Printf("Error sending notifiation: %s", err)
-					return
-				}
-				resp.Body.Close()
-			}(id)
-			<-time.After(*cfgSocialDelay)
-		}
-	}(*info)
-
-	return
-}
@@ -0,0 +1,27 @@
+package Search
+
+import (
+	"github.com/300brand/coverageservices/types"
+	"labix.org/v2/mgo/bson"
+)
+
+// IDs of publications removed through Publication.Remove which still have
+// articles to match. With among set, only those publications are checked.
+func (s *Service) deletedPublications(among []bson.ObjectId) (ids []bson.ObjectId, err error) {
+	query := &types.MultiQuery{
+		Query:  bson.M{"deleted": bson.M{"$exists": true}, "numarticles": bson.M{"$gt": 0}},
+		Select: bson.M{"_id": 1},
+	}
+	if among != nil {
+		query.Query = bson.M{"_id": bson.M{"$in": among}, "deleted": bson.M{"$exists": true}}
+	}
+	pubs := new(types.MultiPubs)
+	if err = s.client.Call("StorageReader.Publications", query, pubs); err != nil {
+		return
+	}
+	ids = make([]bson.ObjectId, len(pubs.Publications))
+	for i, p := range pubs.Publications {
+		ids[i] = p.ID
+	}
+	return
+}
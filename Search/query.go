@@ -1,7 +1,13 @@
 package Search
 
 import (
+	"fmt"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/mongosearch"
+	"github.com/300brand/searchquery"
+	"labix.org/v2/mgo/bson"
 	"strings"
+	"time"
 )
 
 func queryV1toV2(in string) (out string) {
@@ -22,3 +28,211 @@ func queryV1toV2(in string) (out string) {
 	quotedQuery = strings.Join(qBits, " NOT ")
 	return quotedQuery
 }
+
+// Pulls the positive terms out of a V2 query so matching sentences can be
+// found in results. Terms following NOT (including whole groups) are
+// skipped, as are field prefixes such as keywords:
+func queryTerms(in string) (terms []string) {
+	var (
+		tokens []string
+		runes  = []rune(in)
+	)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == '(' || r == ')':
+			tokens = append(tokens, string(r))
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			tokens = append(tokens, `"`+string(runes[i+1:end]))
+			i = end
+		case r == ' ' || r == '\t' || r == '\n':
+		default:
+			end := i
+			for end < len(runes) && !strings.ContainsRune(" \t\n()\"", runes[end]) {
+				end++
+			}
+			tokens = append(tokens, string(runes[i:end]))
+			i = end - 1
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < len(tokens); i++ {
+		switch t := tokens[i]; {
+		case t == "NOT":
+			// Skip the next term or the whole parenthesized group
+			depth := 0
+			for i++; i < len(tokens); i++ {
+				if tokens[i] == "(" {
+					depth++
+				} else if tokens[i] == ")" {
+					depth--
+				}
+				if depth <= 0 {
+					break
+				}
+			}
+		case t == "AND" || t == "OR" || t == "(" || t == ")" || strings.HasSuffix(t, ":"):
+		default:
+			term := strings.TrimPrefix(t, `"`)
+			if term != "" && !seen[term] {
+				seen[term] = true
+				terms = append(terms, term)
+			}
+		}
+	}
+	return
+}
+
+// Converts the query to the V2 format according to its version
+func queryV2(in *types.SearchQuery) (q string, err error) {
+	switch in.Version {
+	case 0, 1:
+		q = queryV1toV2(in.Q)
+	case 2:
+		// Can't decide if the date range should be expected in the input?
+		q = in.Q
+	default:
+		err = fmt.Errorf("Invalid version: %d", in.Version)
+	}
+	return
+}
+
+// Builds the query sent to mongosearch, returning it with the number of
+// publish dates it covers. Articles from excluded publications never match;
+// in.Entities must already be resolved to keys.
+func mongoQuery(in *types.SearchQuery, queryIn string, exclude []bson.ObjectId) (query string, buckets int) {
+	// This is just silly, but most efficient way to calculate
+	dates := []time.Time{}
+	for st, t := in.Dates.Start.AddDate(0, 0, -1), in.Dates.End; t.After(st); t = t.AddDate(0, 0, -1) {
+		dates = append(dates, t)
+	}
+	// Cast dates to string and proper format
+	queryDates := make([]string, len(dates))
+	for i := range dates {
+		queryDates[i] = fmt.Sprintf("'%s'", dates[i].Format(mongosearch.TimeLayout))
+	}
+
+	query = fmt.Sprintf(
+		"published:(%s) AND keywords:(%s)",
+		strings.Join(queryDates, " OR "),
+		queryIn,
+	)
+
+	if len(in.PublicationIds) > 0 {
+		ids := make([]string, len(in.PublicationIds))
+		for i, id := range in.PublicationIds {
+			ids[i] = id.Hex()
+		}
+		query += fmt.Sprintf(" AND publicationid:(%s)", strings.Join(ids, " OR "))
+	}
+	for _, key := range in.Entities {
+		query += fmt.Sprintf(" AND entities:('%s')", key)
+	}
+	if len(exclude) > 0 {
+		ids := make([]string, len(exclude))
+		for i, id := range exclude {
+			ids[i] = id.Hex()
+		}
+		query += fmt.Sprintf(" NOT publicationid:(%s)", strings.Join(ids, " OR "))
+	}
+	return query, len(dates)
+}
+
+// Checks a V2 query's structure, pointing at the first problem, then parses
+// it. Returns the parsed query tree in its normalized form
+func parseQuery(in string) (normalized string, qerr *types.QueryError) {
+	if qerr = checkQuery(in); qerr != nil {
+		return
+	}
+	q, err := searchquery.ParseGreedy(in)
+	if err != nil {
+		return "", &types.QueryError{Offset: -1, Message: err.Error()}
+	}
+	return q.String(), nil
+}
+
+func checkQuery(in string) *types.QueryError {
+	fail := func(offset int, format string, args ...interface{}) *types.QueryError {
+		return &types.QueryError{Offset: offset, Message: fmt.Sprintf(format, args...)}
+	}
+
+	var (
+		opens []int  // Offsets of unclosed parentheses
+		prev  string // Previous token: "(", "op", "NOT", "field" or "term"
+		last  int    // Offset of the previous token
+		runes = []rune(in)
+	)
+	if strings.TrimSpace(in) == "" {
+		return fail(0, "Empty query")
+	}
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == ' ' || r == '\t' || r == '\n':
+			continue
+		case r == '(':
+			opens = append(opens, i)
+			prev = "("
+		case r == ')':
+			if len(opens) == 0 {
+				return fail(i, "Unmatched )")
+			}
+			switch prev {
+			case "(":
+				return fail(last, "Empty group")
+			case "op", "NOT", "field":
+				return fail(last, "Expected a term after %s", string(runes[last:i]))
+			}
+			opens = opens[:len(opens)-1]
+			prev = "term"
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return fail(i, "Unterminated quote")
+			}
+			if end == i+1 {
+				return fail(i, "Empty phrase")
+			}
+			prev = "term"
+			last, i = i, end
+			continue
+		default:
+			end := i
+			for end < len(runes) && !strings.ContainsRune(" \t\n()\"", runes[end]) {
+				end++
+			}
+			switch word := string(runes[i:end]); {
+			case word == "AND" || word == "OR":
+				if prev == "" || prev == "(" || prev == "op" || prev == "NOT" || prev == "field" {
+					return fail(i, "Expected a term before %s", word)
+				}
+				prev = "op"
+			case word == "NOT":
+				if prev == "NOT" || prev == "field" {
+					return fail(i, "Expected a term before NOT")
+				}
+				prev = "NOT"
+			case strings.HasSuffix(word, ":"):
+				prev = "field"
+			default:
+				prev = "term"
+			}
+			last, i = i, end-1
+			continue
+		}
+		last = i
+	}
+	if len(opens) > 0 {
+		return fail(opens[len(opens)-1], "Unclosed (")
+	}
+	if prev == "op" || prev == "NOT" || prev == "field" {
+		return fail(last, "Expected a term after %s", strings.TrimSpace(string(runes[last:])))
+	}
+	return nil
+}
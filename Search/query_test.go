@@ -1,8 +1,13 @@
 package Search
 
 import (
+	"github.com/300brand/coverageservices/types"
 	"github.com/300brand/searchquery"
+	"labix.org/v2/mgo/bson"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 var queryTests = []struct {
@@ -36,3 +41,82 @@ func TestV1toV2(t *testing.T) {
 		}
 	}
 }
+
+var termTests = []struct {
+	In    string
+	Terms []string
+}{
+	{`(("CDW") OR ("CDWG")) NOT ("collision damage waiver")`, []string{"CDW", "CDWG"}},
+	{`"cloud computing" AND (Amazon OR Google) NOT Oracle`, []string{"cloud computing", "Amazon", "Google"}},
+	{`keywords:("big data") OR Hadoop OR Hadoop`, []string{"big data", "Hadoop"}},
+}
+
+func TestQueryTerms(t *testing.T) {
+	for i, test := range termTests {
+		if terms := queryTerms(test.In); !reflect.DeepEqual(terms, test.Terms) {
+			t.Errorf("[%d] Expected: %q", i, test.Terms)
+			t.Errorf("[%d] Got:      %q", i, terms)
+		}
+	}
+}
+
+var checkQueryTests = []struct {
+	In     string
+	Offset int // -1 when valid
+}{
+	{`(("CDW") OR ("CDWG")) NOT ("collision damage waiver")`, -1},
+	{`keywords:(apple OR "big pear") AND NOT banana`, -1},
+	{`apple banana`, -1},
+	{``, 0},
+	{`("apple"`, 0},
+	{`"apple") OR ("pear")`, 7},
+	{`("apple") OR ()`, 13},
+	{`"apple" OR "pear`, 11},
+	{`"" OR "pear"`, 0},
+	{`OR "pear"`, 0},
+	{`"apple" AND`, 8},
+	{`("apple" OR) AND "pear"`, 9},
+	{`"apple" AND OR "pear"`, 12},
+	{`"apple" NOT NOT "pear"`, 12},
+	{`keywords:`, 0},
+}
+
+func TestCheckQuery(t *testing.T) {
+	for i, test := range checkQueryTests {
+		qerr := checkQuery(test.In)
+		switch {
+		case qerr == nil && test.Offset != -1:
+			t.Errorf("[%d] %s: Expected error at %d", i, test.In, test.Offset)
+		case qerr != nil && test.Offset == -1:
+			t.Errorf("[%d] %s: Unexpected error: %s", i, test.In, qerr.Message)
+		case qerr != nil && qerr.Offset != test.Offset:
+			t.Errorf("[%d] %s: Expected error at %d, got %d (%s)", i, test.In, test.Offset, qerr.Offset, qerr.Message)
+		}
+	}
+}
+
+func TestMongoQueryExclude(t *testing.T) {
+	in := &types.SearchQuery{PublicationIds: []bson.ObjectId{bson.NewObjectId()}}
+	in.Dates.Start = time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+	in.Dates.End = in.Dates.Start
+	a, b := bson.NewObjectId(), bson.NewObjectId()
+
+	query, _ := mongoQuery(in, `"apple"`, []bson.ObjectId{a, b})
+	if expect := " NOT publicationid:(" + a.Hex() + " OR " + b.Hex() + ")"; !strings.HasSuffix(query, expect) {
+		t.Errorf("Expected query to end with %q: %s", expect, query)
+	}
+	if query, _ = mongoQuery(in, `"apple"`, nil); strings.Contains(query, "NOT") {
+		t.Errorf("Unexpected exclusion: %s", query)
+	}
+}
+
+func TestMongoQueryEntities(t *testing.T) {
+	in := &types.SearchQuery{Entities: []string{"bank of america", "apple"}}
+	in.Dates.Start = time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+	in.Dates.End = in.Dates.Start
+
+	query, _ := mongoQuery(in, `"apple"`, nil)
+	if expect := " AND entities:('bank of america') AND entities:('apple')"; !strings.HasSuffix(query, expect) {
+		t.Errorf("Expected query to end with %q: %s", expect, query)
+	}
+}
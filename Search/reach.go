@@ -0,0 +1,38 @@
+package Search
+
+import (
+	"github.com/300brand/coverageservices/types"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"time"
+)
+
+// Total readership of the results, using each publication's readership as of
+// the article's publish date
+func (s *Service) searchReach(session *mgo.Session, id bson.ObjectId) (reach int64, err error) {
+	articles := session.DB("300brand_Articles").C("Articles")
+
+	err = forEachResultBatch(session, id, func(batch []bson.ObjectId) (err error) {
+		docs := []struct {
+			PublicationId bson.ObjectId
+			Published     time.Time
+		}{}
+		query := bson.M{"_id": bson.M{"$in": batch}}
+		if err = articles.Find(query).Select(bson.M{"publicationid": 1, "published": 1}).All(&docs); err != nil {
+			return
+		}
+		lookups := &types.ReadershipLookups{Lookups: make([]types.ReadershipLookup, len(docs))}
+		for i, d := range docs {
+			lookups.Lookups[i] = types.ReadershipLookup{PublicationId: d.PublicationId, Date: d.Published}
+		}
+		values := new(types.ReadershipValues)
+		if err = s.client.Call("StorageReader.ReadershipAt", lookups, values); err != nil {
+			return
+		}
+		for _, readers := range values.Readers {
+			reach += readers
+		}
+		return
+	})
+	return
+}
@@ -0,0 +1,34 @@
+package Search
+
+import (
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+)
+
+// Number of result IDs handed to each forEachResultBatch call
+const resultBatch = 1000
+
+// Calls fn with the IDs in Results_<id>, resultBatch at a time. The IDs are
+// read up front so fn may remove results as it goes.
+func forEachResultBatch(session *mgo.Session, id bson.ObjectId, fn func(batch []bson.ObjectId) error) (err error) {
+	ids := []struct {
+		Id bson.ObjectId `bson:"_id"`
+	}{}
+	if err = session.DB("300brand_Search").C("Results_" + id.Hex()).Find(nil).Select(bson.M{"_id": 1}).All(&ids); err != nil {
+		return
+	}
+	for start := 0; start < len(ids); start += resultBatch {
+		end := start + resultBatch
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := make([]bson.ObjectId, 0, end-start)
+		for _, r := range ids[start:end] {
+			batch = append(batch, r.Id)
+		}
+		if err = fn(batch); err != nil {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,172 @@
+package Search
+
+import (
+	"github.com/300brand/coverage"
+	"github.com/300brand/coverageservices/sentiment"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/go-toml-config"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+)
+
+var (
+	cfgSentiment          = config.Bool("Search.sentiment.enabled", true)
+	cfgSentimentSentences = config.Int("Search.sentiment.sentences", 5)
+)
+
+// Returns the sentiment of every result of a search along with the sentences
+// mentioning the search terms
+func (s *Service) Sentiment(in *types.ObjectId, out *types.SearchSentiment) (err error) {
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		return
+	}
+	defer session.Close()
+
+	doc := struct {
+		Terms     []string
+		Sentiment types.SentimentSummary
+	}{}
+	if err = session.DB("300brand_Search").C("Search").FindId(in.Id).Select(bson.M{"terms": 1, "sentiment": 1}).One(&doc); err != nil {
+		return
+	}
+	out.Id, out.Terms, out.Summary = in.Id, doc.Terms, doc.Sentiment
+	out.Results = make([]types.ResultSentiment, 0, out.Summary.Positive+out.Summary.Negative+out.Summary.Neutral)
+	return session.DB("300brand_Search").C("Results_" + in.Id.Hex()).Find(nil).Select(bson.M{
+		"sentiment": 1,
+		"sentences": 1,
+	}).All(&out.Results)
+}
+
+// Combines the sentiment summaries of every search in a group
+func (s *Service) GroupSentiment(in *types.ObjectId, out *types.GroupSentiment) (err error) {
+	info := new(coverage.GroupSearch)
+	if err = s.client.Call("StorageReader.GroupSearch", in, info); err != nil {
+		return
+	}
+
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		return
+	}
+	defer session.Close()
+
+	out.Id = in.Id
+	out.Searches = make([]types.SearchSentiment, len(info.SearchIds))
+	summaries := make([]types.SentimentSummary, len(info.SearchIds))
+	for i, id := range info.SearchIds {
+		out.Searches[i].Id = id
+		if out.Searches[i].Summary, err = searchSentiment(session, id); err != nil {
+			return
+		}
+		summaries[i] = out.Searches[i].Summary
+	}
+	out.Overall = combineSentiment(summaries)
+	return
+}
+
+// Same as searchSentiment, with its own connection
+func (s *Service) sentimentSummary(id bson.ObjectId) (summary types.SentimentSummary, err error) {
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		return
+	}
+	defer session.Close()
+	return searchSentiment(session, id)
+}
+
+// Reads the summary stored on the search record when the search completed
+func searchSentiment(session *mgo.Session, id bson.ObjectId) (summary types.SentimentSummary, err error) {
+	doc := struct{ Sentiment types.SentimentSummary }{}
+	err = session.DB("300brand_Search").C("Search").FindId(id).Select(bson.M{"sentiment": 1}).One(&doc)
+	return doc.Sentiment, err
+}
+
+// Scores every result in Results_<id>, saving the article score and the
+// sentences mentioning any of the terms on each result
+func scoreResults(session *mgo.Session, id bson.ObjectId, terms []string) (summary types.SentimentSummary, err error) {
+	results := session.DB("300brand_Search").C("Results_" + id.Hex())
+	articles := session.DB("300brand_Articles").C("Articles")
+
+	scores := []float64{}
+	err = forEachResultBatch(session, id, func(batch []bson.ObjectId) (err error) {
+		iter := articles.Find(bson.M{"_id": bson.M{"$in": batch}}).Select(bson.M{
+			"sentiment":      1,
+			"text.body.text": 1,
+		}).Iter()
+		for {
+			doc := struct {
+				Id        bson.ObjectId `bson:"_id"`
+				Sentiment *float64
+				Text      struct {
+					Body struct {
+						Text []byte
+					}
+				}
+			}{}
+			if !iter.Next(&doc) {
+				break
+			}
+			text := string(doc.Text.Body.Text)
+			r := types.ResultSentiment{Id: doc.Id}
+			// Articles processed before sentiment scoring existed get
+			// scored on the fly
+			if doc.Sentiment != nil {
+				r.Sentiment = *doc.Sentiment
+			} else {
+				r.Sentiment = sentiment.Score(text)
+			}
+			for _, s := range sentiment.Sentences(text, terms, *cfgSentimentSentences) {
+				r.Sentences = append(r.Sentences, types.SentenceScore{Text: s.Text, Score: s.Score})
+			}
+			set := bson.M{"sentiment": r.Sentiment, "sentences": r.Sentences}
+			if err = results.UpdateId(doc.Id, bson.M{"$set": set}); err != nil {
+				iter.Close()
+				return
+			}
+			scores = append(scores, r.Sentiment)
+		}
+		return iter.Close()
+	})
+	if err != nil {
+		return
+	}
+	summary = summarizeSentiment(scores)
+	return
+}
+
+func summarizeSentiment(scores []float64) (summary types.SentimentSummary) {
+	if len(scores) == 0 {
+		return
+	}
+	var total float64
+	for _, score := range scores {
+		total += score
+		switch {
+		case score > sentiment.Neutral:
+			summary.Positive++
+		case score < -sentiment.Neutral:
+			summary.Negative++
+		default:
+			summary.Neutral++
+		}
+	}
+	summary.Mean = total / float64(len(scores))
+	return
+}
+
+// Merges summaries, weighting each mean by its number of results
+func combineSentiment(summaries []types.SentimentSummary) (overall types.SentimentSummary) {
+	var total float64
+	for _, s := range summaries {
+		n := s.Positive + s.Negative + s.Neutral
+		total += s.Mean * float64(n)
+		overall.Positive += s.Positive
+		overall.Negative += s.Negative
+		overall.Neutral += s.Neutral
+	}
+	if n := overall.Positive + overall.Negative + overall.Neutral; n > 0 {
+		overall.Mean = total / float64(n)
+	}
+	return
+}
@@ -0,0 +1,290 @@
+package Search
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/300brand/coverage"
+	"github.com/300brand/coverage/social"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"github.com/300brand/go-toml-config"
+	"github.com/300brand/logger"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// Articles fetched at once by a single Search.Social run
+	cfgSocialWorkers = config.Int("Search.social.workers", 8)
+	// How long progress of a finished run stays available
+	cfgSocialKeep = config.Duration("Search.social.keep", 24*time.Hour)
+	// How often a run saves its progress and checks for cancellation
+	cfgSocialUpdate = config.Duration("Search.social.update", 5*time.Second)
+)
+
+type socialJob struct {
+	mu       sync.Mutex
+	progress types.SocialProgress
+	quit     chan struct{}
+}
+
+// Stored progress of the latest Search.Social run for a search, keyed by the
+// search. The instance running it saves progress every cfgSocialUpdate and
+// stops once Cancel is set, so any instance may report on or cancel the run.
+type socialDoc struct {
+	Id       bson.ObjectId `bson:"_id"`
+	Progress types.SocialProgress
+	Cancel   bool
+	Updated  time.Time
+}
+
+// Fetches social stats for every article in the search, sending each to
+// Notify.Social as it arrives and a final summary once all are done
+func (s *Service) Social(in *types.ObjectId, out *disgo.NullType) (err error) {
+	info := &coverage.Search{}
+	if err = s.client.Call("StorageReader.Search", in, info); err != nil {
+		return
+	}
+
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		return
+	}
+	defer session.Close()
+	jobs := session.DB("300brand_Search").C("SocialJobs")
+
+	now := time.Now()
+	if err = pruneSocialJobs(jobs, now); err != nil {
+		return
+	}
+	doc := new(socialDoc)
+	switch err = jobs.FindId(info.Id).One(doc); {
+	case err == mgo.ErrNotFound:
+		err = nil
+	case err != nil:
+		return
+	case socialRunning(doc, now):
+		return fmt.Errorf("Social stats already running for search %s", info.Id.Hex())
+	}
+
+	job := &socialJob{
+		progress: types.SocialProgress{
+			SearchId: info.Id,
+			Total:    len(info.Articles),
+			Started:  now,
+		},
+		quit: make(chan struct{}),
+	}
+	if _, err = jobs.UpsertId(info.Id, &socialDoc{Id: info.Id, Progress: job.progress, Updated: now}); err != nil {
+		return
+	}
+
+	go s.socialRun(job, *info)
+	return
+}
+
+// Stops a running Search.Social; articles already being fetched finish
+func (s *Service) SocialCancel(in *types.ObjectId, out *disgo.NullType) (err error) {
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		return
+	}
+	defer session.Close()
+
+	running := bson.M{"_id": in.Id, "progress.completed": time.Time{}}
+	err = session.DB("300brand_Search").C("SocialJobs").Update(running, bson.M{"$set": bson.M{"cancel": true}})
+	if err == mgo.ErrNotFound {
+		return fmt.Errorf("Social stats not running for search %s", in.Id.Hex())
+	}
+	return
+}
+
+// Progress of the latest Search.Social run for the search
+func (s *Service) SocialProgress(in *types.ObjectId, out *types.SocialProgress) (err error) {
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		return
+	}
+	defer session.Close()
+
+	doc := new(socialDoc)
+	err = session.DB("300brand_Search").C("SocialJobs").FindId(in.Id).One(doc)
+	if err == mgo.ErrNotFound {
+		return fmt.Errorf("Social stats never ran for search %s", in.Id.Hex())
+	}
+	*out = doc.Progress
+	return
+}
+
+func (s *Service) socialRun(job *socialJob, info coverage.Search) {
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		logger.Error.Printf("Search.Social: [%s] Connecting to MongoDB: %s", info.Id.Hex(), err)
+		return
+	}
+	defer session.Close()
+	jobs := session.DB("300brand_Search").C("SocialJobs")
+
+	// Saves progress and picks up cancellation from other instances
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(*cfgSocialUpdate)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if saveSocialJob(jobs, job) {
+					job.cancel()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	workers := *cfgSocialWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	ids := make(chan bson.ObjectId)
+	wg := new(sync.WaitGroup)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				start := time.Now()
+				err := s.socialArticle(info, id)
+				if err != nil {
+					logger.Error.Printf("Search.Social: [%s] [A:%s] %s", info.Id.Hex(), id.Hex(), err)
+				}
+				job.record(err)
+				// Each worker paces itself, so throughput grows with workers
+				select {
+				case <-time.After(*cfgSocialDelay - time.Since(start)):
+				case <-job.quit:
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, id := range info.Articles {
+		select {
+		case ids <- id:
+		case <-job.quit:
+			break dispatch
+		}
+	}
+	close(ids)
+	wg.Wait()
+	close(done)
+
+	progress := job.finish()
+	saveSocialJob(jobs, job)
+	logger.Info.Printf("Search.Social: [%s] %d/%d sent, %d failed, cancelled: %v", info.Id.Hex(), progress.Done, progress.Total, progress.Failed, progress.Cancelled)
+	if err := notifySocial(info.Notify.Social, struct {
+		SearchId bson.ObjectId
+		Complete bool
+		Progress types.SocialProgress
+	}{info.Id, true, progress}); err != nil {
+		logger.Error.Printf("Search.Social: [%s] Sending completion: %s", info.Id.Hex(), err)
+	}
+}
+
+func (s *Service) socialArticle(info coverage.Search, id bson.ObjectId) (err error) {
+	a := &coverage.Article{}
+	if err = s.client.Call("StorageReader.Article", types.ObjectId{id}, a); err != nil {
+		return
+	}
+	socialStats := new(types.SocialStats)
+	if err = s.client.Call("Social.Article", a, socialStats); err != nil {
+		return
+	}
+	return notifySocial(info.Notify.Social, struct {
+		ArticleId, SearchId bson.ObjectId
+		Stats               social.Stats
+		Errors              map[string]string `json:",omitempty"`
+	}{id, info.Id, socialStats.Stats, socialStats.Errors})
+}
+
+func notifySocial(url string, v interface{}) (err error) {
+	if url == "" {
+		return
+	}
+	buf := new(bytes.Buffer)
+	if err = json.NewEncoder(buf).Encode(v); err != nil {
+		return
+	}
+	resp, err := http.Post(url, "application/json", buf)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+	return
+}
+
+// Saves the job's progress and reports whether cancelling it was requested
+func saveSocialJob(jobs *mgo.Collection, job *socialJob) (cancel bool) {
+	progress := job.status()
+	change := mgo.Change{
+		Update:    bson.M{"$set": bson.M{"progress": progress, "updated": time.Now()}},
+		ReturnNew: true,
+	}
+	stored := new(socialDoc)
+	if _, err := jobs.FindId(progress.SearchId).Apply(change, stored); err != nil {
+		logger.Error.Printf("Search.Social: [%s] Saving progress: %s", progress.SearchId.Hex(), err)
+		return
+	}
+	return stored.Cancel
+}
+
+// Runs which stopped saving progress died with their instance
+func socialRunning(doc *socialDoc, now time.Time) bool {
+	return doc.Progress.Completed.IsZero() && now.Sub(doc.Updated) < 3**cfgSocialUpdate
+}
+
+// Forgets runs that finished more than cfgSocialKeep ago
+func pruneSocialJobs(jobs *mgo.Collection, now time.Time) (err error) {
+	_, err = jobs.RemoveAll(bson.M{"progress.completed": bson.M{
+		"$gt": time.Time{},
+		"$lt": now.Add(-*cfgSocialKeep),
+	}})
+	return
+}
+
+func (j *socialJob) record(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err != nil {
+		j.progress.Failed++
+	} else {
+		j.progress.Done++
+	}
+}
+
+func (j *socialJob) cancel() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.progress.Cancelled {
+		j.progress.Cancelled = true
+		close(j.quit)
+	}
+}
+
+func (j *socialJob) finish() types.SocialProgress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.progress.Completed = time.Now()
+	return j.progress
+}
+
+func (j *socialJob) status() types.SocialProgress {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progress
+}
@@ -0,0 +1,24 @@
+package Search
+
+import (
+	"github.com/300brand/coverageservices/types"
+	"testing"
+	"time"
+)
+
+func TestSocialRunning(t *testing.T) {
+	now := time.Now()
+	for _, test := range []struct {
+		Name    string
+		Doc     socialDoc
+		Running bool
+	}{
+		{"Saving progress", socialDoc{Updated: now.Add(-*cfgSocialUpdate)}, true},
+		{"Stopped saving", socialDoc{Updated: now.Add(-4 * *cfgSocialUpdate)}, false},
+		{"Finished", socialDoc{Progress: types.SocialProgress{Completed: now}, Updated: now}, false},
+	} {
+		if running := socialRunning(&test.Doc, now); running != test.Running {
+			t.Errorf("%s: expected running=%t", test.Name, test.Running)
+		}
+	}
+}
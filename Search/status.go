@@ -0,0 +1,220 @@
+package Search
+
+import (
+	"fmt"
+	"github.com/300brand/coverage"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/go-toml-config"
+	"github.com/300brand/logger"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"os"
+	"time"
+)
+
+var (
+	cfgResultsLimit    = config.Int("Search.results.limit", 50)
+	cfgResultsMaxLimit = config.Int("Search.results.maxlimit", 500)
+
+	// Returned by Search.Results when no fields are requested
+	defaultResultFields = []string{"title", "author", "url", "published", "publicationid"}
+
+	// Recorded on running searches so a restart only interrupts its own
+	instance, _ = os.Hostname()
+)
+
+const (
+	stateRunning     = "running"
+	stateCompleted   = "completed"
+	stateFailed      = "failed"
+	stateCancelled   = "cancelled"
+	stateInterrupted = "interrupted" // Still running when its instance last stopped
+)
+
+type searchDoc struct {
+	Id        bson.ObjectId `bson:"_id"`
+	Start     time.Time
+	Completed time.Time
+	Cancelled time.Time
+	Results   int
+	Error     string
+	State     string
+}
+
+func (s *Service) Status(in *types.ObjectId, out *types.SearchStatus) (err error) {
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		return
+	}
+	defer session.Close()
+
+	doc, err := s.searchDoc(session, in.Id)
+	if err != nil {
+		return
+	}
+	*out = types.SearchStatus{
+		Id:        doc.Id,
+		State:     stateOf(doc),
+		Start:     doc.Start,
+		Completed: doc.Completed,
+		Results:   doc.Results,
+		Error:     doc.Error,
+	}
+	return
+}
+
+// Returns a page of a completed search's articles in article ID order, with
+// only the requested fields
+func (s *Service) Results(in *types.SearchResultsQuery, out *types.SearchResults) (err error) {
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		return
+	}
+	defer session.Close()
+
+	doc, err := s.searchDoc(session, in.Id)
+	if err != nil {
+		return
+	}
+	out.Id, out.Completed, out.Offset = doc.Id, doc.Completed, in.Offset
+	if out.Ready = stateOf(doc) == stateCompleted; !out.Ready {
+		return
+	}
+
+	limit := in.Limit
+	switch {
+	case limit <= 0:
+		limit = *cfgResultsLimit
+	case limit > *cfgResultsMaxLimit:
+		return fmt.Errorf("Limit %d over maximum of %d", limit, *cfgResultsMaxLimit)
+	}
+	if in.Offset < 0 {
+		return fmt.Errorf("Invalid offset: %d", in.Offset)
+	}
+
+	results := session.DB("300brand_Search").C("Results_" + doc.Id.Hex())
+	if out.Total, err = results.Count(); err != nil {
+		return
+	}
+	ids := []struct {
+		Id bson.ObjectId `bson:"_id"`
+	}{}
+	if err = results.Find(nil).Sort("_id").Skip(in.Offset).Limit(limit).Select(bson.M{"_id": 1}).All(&ids); err != nil {
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	page := make([]bson.ObjectId, len(ids))
+	for i := range ids {
+		page[i] = ids[i].Id
+	}
+	fields := in.Fields
+	if len(fields) == 0 {
+		fields = defaultResultFields
+	}
+	selector := bson.M{"_id": 1}
+	for _, f := range fields {
+		selector[f] = 1
+	}
+	articles := []coverage.Article{}
+	query := bson.M{"_id": bson.M{"$in": page}}
+	if err = session.DB("300brand_Articles").C("Articles").Find(query).Select(selector).All(&articles); err != nil {
+		return
+	}
+
+	// Articles removed since the search ran are skipped
+	byId := make(map[bson.ObjectId]coverage.Article, len(articles))
+	for _, a := range articles {
+		byId[a.ID] = a
+	}
+	out.Articles = make([]coverage.Article, 0, len(page))
+	for _, id := range page {
+		if a, ok := byId[id]; ok {
+			out.Articles = append(out.Articles, a)
+		}
+	}
+	return
+}
+
+// Records why a search stopped before completing
+func (s *Service) fail(id bson.ObjectId, cause error) {
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		logger.Error.Printf("Error connecting to MongoDB: %s", err)
+		return
+	}
+	defer session.Close()
+
+	set := bson.M{"error": cause.Error(), "state": stateFailed}
+	if err := session.DB("300brand_Search").C("Search").UpdateId(id, bson.M{"$set": set}); err != nil {
+		logger.Error.Printf("Error recording failure of search %s: %s", id.Hex(), err)
+	}
+}
+
+// Records the state of a search, and the instance running it, so Status
+// survives service restarts
+func (s *Service) setState(id bson.ObjectId, state string) {
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		logger.Error.Printf("Error connecting to MongoDB: %s", err)
+		return
+	}
+	defer session.Close()
+
+	if err := session.DB("300brand_Search").C("Search").UpdateId(id, bson.M{"$set": bson.M{"state": state, "host": instance}}); err != nil {
+		logger.Error.Printf("Error recording state of search %s: %s", id.Hex(), err)
+	}
+}
+
+func (s *Service) searchDoc(session *mgo.Session, id bson.ObjectId) (doc searchDoc, err error) {
+	err = session.DB("300brand_Search").C("Search").FindId(id).Select(bson.M{
+		"start":     1,
+		"completed": 1,
+		"cancelled": 1,
+		"results":   1,
+		"error":     1,
+		"state":     1,
+	}).One(&doc)
+	return
+}
+
+// Searches this host left running before a restart will never finish; marks
+// them interrupted. Other hosts' searches are left alone.
+func (s *Service) interruptRuns() {
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		logger.Error.Printf("Error connecting to MongoDB: %s", err)
+		return
+	}
+	defer session.Close()
+
+	info, err := session.DB("300brand_Search").C("Search").UpdateAll(
+		bson.M{"state": stateRunning, "host": instance},
+		bson.M{"$set": bson.M{"state": stateInterrupted}},
+	)
+	if err != nil {
+		logger.Error.Printf("Error marking running searches interrupted: %s", err)
+		return
+	}
+	if info.Updated > 0 {
+		logger.Warn.Printf("Marked %d searches interrupted", info.Updated)
+	}
+}
+
+// Searches record their state as it changes; older documents only carry the
+// timestamps, from which the state is derived
+func stateOf(doc searchDoc) string {
+	switch {
+	case doc.State != "":
+		return doc.State
+	case !doc.Cancelled.IsZero():
+		return stateCancelled
+	case doc.Error != "":
+		return stateFailed
+	case !doc.Completed.IsZero():
+		return stateCompleted
+	}
+	return stateInterrupted
+}
@@ -0,0 +1,27 @@
+package Search
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStateOf(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		Name  string
+		Doc   searchDoc
+		State string
+	}{
+		{"Recorded", searchDoc{State: stateRunning}, stateRunning},
+		{"Recorded over timestamps", searchDoc{State: stateInterrupted, Completed: now}, stateInterrupted},
+		{"Legacy cancelled", searchDoc{Cancelled: now, Completed: now}, stateCancelled},
+		{"Legacy failed", searchDoc{Error: "boom", Completed: now}, stateFailed},
+		{"Legacy completed", searchDoc{Completed: now}, stateCompleted},
+		{"Legacy unfinished", searchDoc{Start: now}, stateInterrupted},
+	}
+	for _, test := range tests {
+		if got := stateOf(test.Doc); got != test.State {
+			t.Errorf("%s: got %q, expected %q", test.Name, got, test.State)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package Search
+
+import (
+	"github.com/300brand/coverage/article/lexer"
+	"github.com/300brand/coverageservices/types"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+)
+
+// Checks a query without running it. Invalid queries are reported in the
+// result rather than as an error so the position of the problem reaches the
+// caller
+func (s *Service) Validate(in *types.SearchValidation, out *types.SearchValidationResult) (err error) {
+	q := in.Query
+	if out.V2, err = queryV2(&q); err != nil {
+		return
+	}
+	if out.Normalized, out.Error = parseQuery(out.V2); out.Error != nil {
+		return
+	}
+	out.Valid = true
+	out.Terms = queryTerms(out.V2)
+	if !in.Explain {
+		return
+	}
+
+	include, exclude, err := s.resolvePublications(&q)
+	if err != nil {
+		return
+	}
+	q.PublicationIds = include
+	if err = s.resolveEntities(&q); err != nil {
+		return
+	}
+
+	out.Explain = new(types.SearchExplain)
+	out.Explain.Query, out.Explain.Buckets = mongoQuery(&q, out.V2, exclude)
+	out.Explain.Estimate, err = estimate(&q, out.Terms)
+	return
+}
+
+// Counts articles in the date range and publications containing every
+// keyword of at least one term
+func estimate(in *types.SearchQuery, terms []string) (n int, err error) {
+	if len(terms) == 0 {
+		return
+	}
+	session, err := mgo.Dial(*cfgMongoServer)
+	if err != nil {
+		return
+	}
+	defer session.Close()
+
+	or := make([]bson.M, 0, len(terms))
+	for _, t := range terms {
+		if keywords := lexer.Keywords([]byte(t)); len(keywords) > 0 {
+			or = append(or, bson.M{"text.words.keywords": bson.M{"$all": keywords}})
+		}
+	}
+	if len(or) == 0 {
+		return
+	}
+	query := bson.M{
+		"published": bson.M{
+			"$gte": in.Dates.Start,
+			"$lt":  in.Dates.End.AddDate(0, 0, 1),
+		},
+		"$or": or,
+	}
+	if len(in.PublicationIds) > 0 {
+		query["publicationid"] = bson.M{"$in": in.PublicationIds}
+	}
+	return session.DB("300brand_Articles").C("Articles").Find(query).Count()
+}
@@ -4,12 +4,25 @@ import (
 	"github.com/300brand/coverage"
 	"github.com/300brand/coverage/social"
 	"github.com/300brand/coverageservices/service"
+	"github.com/300brand/coverageservices/types"
 	"github.com/300brand/disgo"
+	"github.com/300brand/go-toml-config"
+	"github.com/300brand/logger"
+	"labix.org/v2/mgo/bson"
+	"time"
 )
 
-type Service struct{}
+type Service struct {
+	client    *disgo.Client
+	providers []Provider
+}
+
+var (
+	_ service.Service = new(Service)
 
-var _ service.Service = new(Service)
+	// Stats fetched more recently than this are served from the database
+	cfgTTL = config.Duration("Social.ttl", 6*time.Hour)
+)
 
 func init() {
 	service.Register("Social", new(Service))
@@ -17,10 +30,71 @@ func init() {
 
 // Funcs required for Service
 
-func (s *Service) Start(client *disgo.Client) (err error) { return }
+func (s *Service) Start(client *disgo.Client) (err error) {
+	s.client = client
+	if s.providers, err = loadProviders(); err != nil {
+		return
+	}
+	if _, err = pollOffsets(*cfgPollOffsets); err != nil {
+		return
+	}
+	if *cfgPollTick > 0 {
+		go s.pollLoop()
+	}
+	return
+}
 
 // Service funcs
 
-func (s *Service) Article(in *coverage.Article, out *social.Stats) (err error) {
-	return social.FetchString(in.URL, out)
+// Returns cached stats when fresh and complete, otherwise fetches and records
+// a new snapshot
+func (s *Service) Article(in *coverage.Article, out *types.SocialStats) (err error) {
+	cached := new(types.SocialSnapshot)
+	found := s.client.Call("StorageReader.SocialSnapshot", &types.ObjectId{in.ID}, cached) == nil
+	if found && len(cached.Failed) == 0 && time.Since(cached.Fetched) < *cfgTTL {
+		s.client.Call("Stats.Increment", &types.Stat{Name: "Social.Cache.Hit", Count: 1}, disgo.Null)
+		out.Stats = cached.Stats
+		return
+	}
+	s.client.Call("Stats.Increment", &types.Stat{Name: "Social.Cache.Miss", Count: 1}, disgo.Null)
+	return s.Refresh(in, out)
+}
+
+// Every snapshot of the article's stats, oldest first
+func (s *Service) History(in *types.ObjectId, out *types.SocialHistory) (err error) {
+	return s.client.Call("StorageReader.SocialHistory", in, out)
+}
+
+// Fetches stats regardless of the cache and records them. Networks that
+// failed are listed in out.Errors.
+func (s *Service) Refresh(in *coverage.Article, out *types.SocialStats) (err error) {
+	stats, failed, err := s.fetch(in.ID, in.URL, 0)
+	if err != nil {
+		return
+	}
+	out.Stats = stats
+	for field, ferr := range failed {
+		if out.Errors == nil {
+			out.Errors = make(map[string]string, len(failed))
+		}
+		out.Errors[field] = ferr.Error()
+	}
+	return
+}
+
+func (s *Service) fetch(id bson.ObjectId, url string, offset time.Duration) (stats social.Stats, failed map[string]error, err error) {
+	if stats, failed, err = collect(s.providers, url); err != nil {
+		return
+	}
+	snapshot := &types.SocialSnapshot{
+		ArticleId: id,
+		Stats:     stats,
+		Fetched:   time.Now(),
+		Offset:    offset,
+		Failed:    failedFields(failed),
+	}
+	if err := s.client.Call("StorageWriter.SocialSnapshot", snapshot, disgo.Null); err != nil {
+		logger.Error.Printf("Social: [A:%s] Saving snapshot: %s", id.Hex(), err)
+	}
+	return
 }
@@ -0,0 +1,197 @@
+package Social
+
+import (
+	"github.com/300brand/coverage"
+	"github.com/300brand/coverage/social"
+	"github.com/300brand/coverageservices/backoff"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/disgo"
+	"github.com/300brand/go-toml-config"
+	"github.com/300brand/logger"
+	"labix.org/v2/mgo/bson"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// Comma-separated offsets after publication at which stats are fetched
+	cfgPollOffsets = config.String("Social.poll.offsets", "1h,24h,168h")
+	// How often due polls are checked for; 0 disables polling
+	cfgPollTick = config.Duration("Social.poll.tick", time.Minute)
+	// Failed fetches are retried after backoff, doubled after every failure
+	// up to maxbackoff, until attempts is reached
+	cfgPollAttempts   = config.Int("Social.poll.attempts", 5)
+	cfgPollBackoff    = config.Duration("Social.poll.backoff", 5*time.Minute)
+	cfgPollMaxBackoff = config.Duration("Social.poll.maxbackoff", 6*time.Hour)
+)
+
+type byOffset []types.SocialPoint
+
+func (b byOffset) Len() int           { return len(b) }
+func (b byOffset) Less(i, j int) bool { return b[i].Offset < b[j].Offset }
+func (b byOffset) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+
+// Queues polls of the article at each configured offset that is still in
+// the future
+func (s *Service) Schedule(in *types.SocialSchedule, out *disgo.NullType) (err error) {
+	offsets, err := pollOffsets(*cfgPollOffsets)
+	if err != nil {
+		return
+	}
+	published := in.Published
+	if published.IsZero() {
+		published = time.Now()
+	}
+	for _, offset := range offsets {
+		poll := &types.SocialPoll{
+			ArticleId: in.ArticleId,
+			URL:       in.URL,
+			Offset:    offset,
+			Due:       published.Add(offset),
+		}
+		if poll.Due.Before(time.Now()) {
+			continue
+		}
+		if err = s.client.Call("StorageWriter.SocialPollAdd", poll, disgo.Null); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Sums the stats of a search's articles at each poll offset
+func (s *Service) Search(in *types.ObjectId, out *types.SocialSeries) (err error) {
+	info := new(coverage.Search)
+	if err = s.client.Call("StorageReader.Search", in, info); err != nil {
+		return
+	}
+	snapshots := new(types.SocialSnapshots)
+	if err = s.client.Call("StorageReader.SocialSnapshots", &types.ObjectIds{info.Articles}, snapshots); err != nil {
+		return
+	}
+	*out = series(info.Id, snapshots.Snapshots)
+	return
+}
+
+// Snapshots must be oldest first so the latest of each article wins. Fields
+// whose network failed keep the article's previous count.
+func series(searchId bson.ObjectId, snapshots []types.SocialSnapshot) (out types.SocialSeries) {
+	out.SearchId = searchId
+	points := make(map[time.Duration]*types.SocialPoint)
+	latest := make(map[string]social.Stats)
+	for i := range snapshots {
+		snap := &snapshots[i]
+		stats := snap.Stats
+		keepStats(&stats, latest[snap.ArticleId.Hex()], snap.Failed)
+		latest[snap.ArticleId.Hex()] = stats
+		if snap.Offset == 0 {
+			continue
+		}
+		p, ok := points[snap.Offset]
+		if !ok {
+			p = &types.SocialPoint{Offset: snap.Offset}
+			points[snap.Offset] = p
+		}
+		p.Articles++
+		addStats(&p.Stats, stats)
+	}
+	for _, p := range points {
+		out.Points = append(out.Points, *p)
+	}
+	sort.Sort(byOffset(out.Points))
+	for _, stats := range latest {
+		out.Latest.Articles++
+		addStats(&out.Latest.Stats, stats)
+	}
+	return
+}
+
+// Fetches stats for every poll that has come due
+func (s *Service) pollLoop() {
+	for _ = range time.Tick(*cfgPollTick) {
+		for {
+			poll := new(types.SocialPoll)
+			err := s.client.Call("StorageWriter.SocialPollNext", &types.DateThreshold{time.Now()}, poll)
+			if err != nil {
+				logger.Error.Printf("Social.poll: %s", err)
+				break
+			}
+			if poll.ArticleId == "" {
+				break
+			}
+			_, failed, err := s.fetch(poll.ArticleId, poll.URL, poll.Offset)
+			if err != nil {
+				logger.Error.Printf("Social.poll: [A:%s] %s: %s", poll.ArticleId.Hex(), poll.Offset, err)
+				s.retry(poll)
+				continue
+			}
+			if len(failed) > 0 {
+				logger.Warn.Printf("Social.poll: [A:%s] %s: Partial stats: %s", poll.ArticleId.Hex(), poll.Offset, failedList(failed))
+			}
+			s.client.Call("Stats.Increment", &types.Stat{Name: "Social.Poll", Count: 1}, disgo.Null)
+		}
+	}
+}
+
+// Puts a failed poll back with a later due time, or drops it once out of
+// attempts. The snapshot keeps its scheduled offset either way.
+func (s *Service) retry(poll *types.SocialPoll) {
+	poll.Attempts++
+	if poll.Attempts >= *cfgPollAttempts {
+		s.client.Call("Stats.Increment", &types.Stat{Name: "Social.Poll.GaveUp", Count: 1}, disgo.Null)
+		logger.Warn.Printf("Social.poll: [A:%s] %s: Giving up after %d attempts", poll.ArticleId.Hex(), poll.Offset, poll.Attempts)
+		return
+	}
+	poll.Due = time.Now().Add(backoff.Delay(poll.Attempts, *cfgPollBackoff, *cfgPollMaxBackoff))
+	if err := s.client.Call("StorageWriter.SocialPollAdd", poll, disgo.Null); err != nil {
+		logger.Error.Printf("Social.poll: [A:%s] %s: Rescheduling: %s", poll.ArticleId.Hex(), poll.Offset, err)
+	}
+}
+
+func pollOffsets(s string) (offsets []time.Duration, err error) {
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		d, err := time.ParseDuration(field)
+		if err != nil {
+			return nil, err
+		}
+		offsets = append(offsets, d)
+	}
+	return
+}
+
+// Copies the named fields of prev into dst
+func keepStats(dst *social.Stats, prev social.Stats, fields []string) {
+	d, p := reflect.ValueOf(dst).Elem(), reflect.ValueOf(prev)
+	for _, field := range fields {
+		if f := d.FieldByName(field); f.IsValid() && f.CanSet() {
+			f.Set(p.FieldByName(field))
+		}
+	}
+}
+
+// Adds every numeric field of src into dst
+func addStats(dst *social.Stats, src social.Stats) {
+	addValue(reflect.ValueOf(dst).Elem(), reflect.ValueOf(src))
+}
+
+func addValue(dst, src reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		dst.SetInt(dst.Int() + src.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		dst.SetUint(dst.Uint() + src.Uint())
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(dst.Float() + src.Float())
+	case reflect.Struct:
+		for i := 0; i < dst.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				addValue(dst.Field(i), src.Field(i))
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package Social
+
+import (
+	"github.com/300brand/coverage/social"
+	"github.com/300brand/coverageservices/types"
+	"labix.org/v2/mgo/bson"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPollOffsets(t *testing.T) {
+	for _, test := range []struct {
+		In      string
+		Offsets []time.Duration
+		Err     bool
+	}{
+		{"1h,24h,168h", []time.Duration{time.Hour, 24 * time.Hour, 168 * time.Hour}, false},
+		{" 30m , 2h ", []time.Duration{30 * time.Minute, 2 * time.Hour}, false},
+		{"1h,,2h,", []time.Duration{time.Hour, 2 * time.Hour}, false},
+		{"", nil, false},
+		{"1h,1 day", nil, true},
+	} {
+		offsets, err := pollOffsets(test.In)
+		if (err != nil) != test.Err {
+			t.Errorf("%q: Unexpected error state: %v", test.In, err)
+			continue
+		}
+		if !reflect.DeepEqual(offsets, test.Offsets) {
+			t.Errorf("%q: Expected %v, got %v", test.In, test.Offsets, offsets)
+		}
+	}
+}
+
+func TestAddStats(t *testing.T) {
+	for _, test := range []struct {
+		A, B, Sum social.Stats
+	}{
+		{social.Stats{}, social.Stats{}, social.Stats{}},
+		{social.Stats{Facebook: 1}, social.Stats{Facebook: 2, Twitter: 3}, social.Stats{Facebook: 3, Twitter: 3}},
+		{social.Stats{LinkedIn: 5, Twitter: 1}, social.Stats{LinkedIn: 5}, social.Stats{LinkedIn: 10, Twitter: 1}},
+	} {
+		sum := test.A
+		addStats(&sum, test.B)
+		if sum != test.Sum {
+			t.Errorf("%+v + %+v: Expected %+v, got %+v", test.A, test.B, test.Sum, sum)
+		}
+	}
+}
+
+func TestSeries(t *testing.T) {
+	search, a, b := bson.NewObjectId(), bson.NewObjectId(), bson.NewObjectId()
+	snapshots := []types.SocialSnapshot{
+		{ArticleId: a, Offset: time.Hour, Stats: social.Stats{Twitter: 1}},
+		{ArticleId: b, Offset: time.Hour, Stats: social.Stats{Twitter: 2}},
+		{ArticleId: a, Offset: 0, Stats: social.Stats{Twitter: 4}},
+		{ArticleId: a, Offset: 24 * time.Hour, Stats: social.Stats{Twitter: 5, Facebook: 1}},
+	}
+	out := series(search, snapshots)
+	if out.SearchId != search {
+		t.Errorf("SearchId not set")
+	}
+
+	expect := []types.SocialPoint{
+		{Offset: time.Hour, Articles: 2, Stats: social.Stats{Twitter: 3}},
+		{Offset: 24 * time.Hour, Articles: 1, Stats: social.Stats{Twitter: 5, Facebook: 1}},
+	}
+	if !reflect.DeepEqual(out.Points, expect) {
+		t.Errorf("Points: Expected %+v, got %+v", expect, out.Points)
+	}
+
+	// On-demand fetches count toward the latest totals but not the points
+	latest := types.SocialPoint{Articles: 2, Stats: social.Stats{Twitter: 7, Facebook: 1}}
+	if out.Latest != latest {
+		t.Errorf("Latest: Expected %+v, got %+v", latest, out.Latest)
+	}
+}
+
+func TestSeriesFailed(t *testing.T) {
+	a := bson.NewObjectId()
+	snapshots := []types.SocialSnapshot{
+		{ArticleId: a, Offset: time.Hour, Stats: social.Stats{Twitter: 3, Facebook: 2}},
+		{ArticleId: a, Offset: 24 * time.Hour, Stats: social.Stats{Twitter: 6}, Failed: []string{"Facebook"}},
+	}
+	out := series(bson.NewObjectId(), snapshots)
+
+	// A failed network keeps the previous count rather than dropping to 0
+	expect := social.Stats{Twitter: 6, Facebook: 2}
+	if len(out.Points) != 2 || out.Points[1].Stats != expect {
+		t.Errorf("Points: Expected %+v at 24h, got %+v", expect, out.Points)
+	}
+	if out.Latest.Stats != expect {
+		t.Errorf("Latest: Expected %+v, got %+v", expect, out.Latest.Stats)
+	}
+}
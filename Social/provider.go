@@ -0,0 +1,192 @@
+package Social
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/300brand/coverage/social"
+	"github.com/300brand/go-toml-config"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A social network that reports how often a URL was shared
+type Provider interface {
+	Field() string // social.Stats field the count is stored in
+	Count(url string) (int, error)
+}
+
+// Provider reading a count from a JSON endpoint. Endpoint may contain {url}
+// and {key}, replaced with the escaped article URL and the credential
+type httpProvider struct {
+	field    string
+	endpoint string
+	key      string
+	path     []string // Keys leading to the count in the response
+	rate     time.Duration
+	client   *http.Client
+	mu       sync.Mutex
+	last     time.Time
+}
+
+// Config of a network under [Social.<name>]
+type providerConfig struct {
+	field    string
+	enabled  *bool
+	endpoint *string
+	key      *string
+	path     *string
+	rate     *time.Duration
+	timeout  *time.Duration
+}
+
+var providerConfigs = []providerConfig{
+	newProviderConfig("facebook", "Facebook", "http://graph.facebook.com/?id={url}", "shares"),
+	newProviderConfig("linkedin", "LinkedIn", "http://www.linkedin.com/countserv/count/share?format=json&url={url}", "count"),
+	newProviderConfig("twitter", "Twitter", "http://urls.api.twitter.com/1/urls/count.json?url={url}", "count"),
+}
+
+var _ Provider = new(httpProvider)
+
+func newProviderConfig(name, field, endpoint, path string) providerConfig {
+	prefix := "Social." + name + "."
+	return providerConfig{
+		field:    field,
+		enabled:  config.Bool(prefix+"enabled", true),
+		endpoint: config.String(prefix+"endpoint", endpoint),
+		key:      config.String(prefix+"key", ""),
+		path:     config.String(prefix+"path", path),
+		rate:     config.Duration(prefix+"rate", 0),
+		timeout:  config.Duration(prefix+"timeout", 10*time.Second),
+	}
+}
+
+func newHTTPProvider(field, endpoint, key, path string, rate, timeout time.Duration) Provider {
+	return &httpProvider{
+		field:    field,
+		endpoint: endpoint,
+		key:      key,
+		path:     strings.Split(path, "."),
+		rate:     rate,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Builds the enabled providers, checking each stores into a usable field
+func loadProviders() (providers []Provider, err error) {
+	for _, c := range providerConfigs {
+		if !*c.enabled {
+			continue
+		}
+		p := newHTTPProvider(c.field, *c.endpoint, *c.key, *c.path, *c.rate, *c.timeout)
+		if err = checkField(p.Field()); err != nil {
+			return
+		}
+		providers = append(providers, p)
+	}
+	return
+}
+
+// Asks every provider for its count. Networks that fail are returned in
+// failed, keyed by field, and left out of stats; an error is only returned
+// when all of them fail
+func collect(providers []Provider, u string) (stats social.Stats, failed map[string]error, err error) {
+	v := reflect.ValueOf(&stats).Elem()
+	for _, p := range providers {
+		n, perr := p.Count(u)
+		if perr != nil {
+			if failed == nil {
+				failed = make(map[string]error)
+			}
+			failed[p.Field()] = perr
+			continue
+		}
+		v.FieldByName(p.Field()).SetInt(int64(n))
+	}
+	if len(providers) > 0 && len(failed) == len(providers) {
+		err = fmt.Errorf("All providers failed: %s", failedList(failed))
+	}
+	return
+}
+
+// Failed networks as "Field: error" pairs in field order
+func failedList(failed map[string]error) string {
+	msgs := make([]string, 0, len(failed))
+	for _, field := range failedFields(failed) {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", field, failed[field]))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func failedFields(failed map[string]error) (fields []string) {
+	for field := range failed {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return
+}
+
+func checkField(field string) error {
+	f := reflect.ValueOf(new(social.Stats)).Elem().FieldByName(field)
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return nil
+	}
+	return fmt.Errorf("social.Stats has no integer field %q", field)
+}
+
+func (p *httpProvider) Field() string { return p.field }
+
+func (p *httpProvider) Count(u string) (n int, err error) {
+	p.wait()
+	endpoint := strings.NewReplacer("{url}", url.QueryEscape(u), "{key}", url.QueryEscape(p.key)).Replace(p.endpoint)
+	resp, err := p.client.Get(endpoint)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s returned %s", p.field, resp.Status)
+	}
+	var body interface{}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return
+	}
+	return countAt(body, p.path)
+}
+
+// Blocks until rate has passed since the previous request
+func (p *httpProvider) wait() {
+	if p.rate <= 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if d := p.rate - time.Since(p.last); d > 0 {
+		time.Sleep(d)
+	}
+	p.last = time.Now()
+}
+
+// Follows path through decoded JSON objects. A missing key counts as zero,
+// which is how networks report URLs they have never seen
+func countAt(body interface{}, path []string) (n int, err error) {
+	for _, key := range path {
+		obj, ok := body.(map[string]interface{})
+		if !ok {
+			return 0, fmt.Errorf("Expected an object at %q", key)
+		}
+		if body, ok = obj[key]; !ok {
+			return 0, nil
+		}
+	}
+	f, ok := body.(float64)
+	if !ok {
+		return 0, fmt.Errorf("Expected a number at %q", strings.Join(path, "."))
+	}
+	return int(f), nil
+}
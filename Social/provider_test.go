@@ -0,0 +1,138 @@
+package Social
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Fake networks keyed by path, each answering with a fixed JSON body for
+// every URL except those in missing, which get an empty object
+func fakeNetworks(bodies map[string]string, missing string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("url") == missing {
+			body = "{}"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+}
+
+func TestCollect(t *testing.T) {
+	ts := fakeNetworks(map[string]string{
+		"/fb": `{"id":"x","shares":12}`,
+		"/tw": `{"count":7}`,
+		"/li": `{"share":{"count":3}}`,
+	}, "")
+	defer ts.Close()
+
+	providers := []Provider{
+		newHTTPProvider("Facebook", ts.URL+"/fb?url={url}", "", "shares", 0, time.Second),
+		newHTTPProvider("Twitter", ts.URL+"/tw?url={url}", "", "count", 0, time.Second),
+		newHTTPProvider("LinkedIn", ts.URL+"/li?url={url}", "", "share.count", 0, time.Second),
+	}
+	stats, failed, err := collect(providers, "http://example.com/a?b=c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 0 {
+		t.Errorf("Unexpected failures: %v", failed)
+	}
+	if stats.Facebook != 12 || stats.Twitter != 7 || stats.LinkedIn != 3 {
+		t.Errorf("Got %+v", stats)
+	}
+}
+
+func TestCollectPartialFailure(t *testing.T) {
+	ts := fakeNetworks(map[string]string{"/tw": `{"count":7}`}, "")
+	defer ts.Close()
+
+	providers := []Provider{
+		newHTTPProvider("Facebook", ts.URL+"/down?url={url}", "", "shares", 0, time.Second),
+		newHTTPProvider("Twitter", ts.URL+"/tw?url={url}", "", "count", 0, time.Second),
+	}
+	stats, failed, err := collect(providers, "http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Facebook != 0 || stats.Twitter != 7 {
+		t.Errorf("Got %+v", stats)
+	}
+	if fields := failedFields(failed); !reflect.DeepEqual(fields, []string{"Facebook"}) {
+		t.Errorf("Expected Facebook to fail, got %v", fields)
+	}
+
+	if _, _, err = collect(providers[:1], "http://example.com/"); err == nil {
+		t.Error("Expected an error when every provider fails")
+	}
+}
+
+func TestCountMissing(t *testing.T) {
+	ts := fakeNetworks(map[string]string{"/fb": `{"shares":12}`}, "http://example.com/new")
+	defer ts.Close()
+
+	p := newHTTPProvider("Facebook", ts.URL+"/fb?url={url}", "", "shares", 0, time.Second)
+	n, err := p.Count("http://example.com/new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("Expected 0 for an unknown URL, got %d", n)
+	}
+}
+
+func TestCountKey(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "secret" {
+			http.Error(w, "bad key", http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]int{"count": 5})
+	}))
+	defer ts.Close()
+
+	p := newHTTPProvider("Twitter", ts.URL+"/?url={url}&key={key}", "secret", "count", 0, time.Second)
+	if n, err := p.Count("http://example.com/"); err != nil || n != 5 {
+		t.Errorf("Got %d, %v", n, err)
+	}
+
+	p = newHTTPProvider("Twitter", ts.URL+"/?url={url}&key={key}", "wrong", "count", 0, time.Second)
+	if _, err := p.Count("http://example.com/"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected a 403 error, got %v", err)
+	}
+}
+
+func TestCountRate(t *testing.T) {
+	ts := fakeNetworks(map[string]string{"/tw": `{"count":1}`}, "")
+	defer ts.Close()
+
+	rate := 50 * time.Millisecond
+	p := newHTTPProvider("Twitter", ts.URL+"/tw?url={url}", "", "count", rate, time.Second)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := p.Count("http://example.com/"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 2*rate {
+		t.Errorf("Three requests took %s, expected at least %s", d, 2*rate)
+	}
+}
+
+func TestCheckField(t *testing.T) {
+	if err := checkField("Twitter"); err != nil {
+		t.Error(err)
+	}
+	if err := checkField("Myspace"); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/300brand/disgo"
 	"github.com/300brand/go-toml-config"
 	"github.com/300brand/logger"
+	"labix.org/v2/mgo"
+	"labix.org/v2/mgo/bson"
+	"time"
 )
 
 type StorageReader struct {
@@ -51,6 +54,28 @@ func (s *StorageReader) Article(in *types.ObjectId, out *coverage.Article) error
 	return s.m.GetArticle(in.Id, out)
 }
 
+func (s *StorageReader) ArticleArchive(in *types.ObjectId, out *types.ArticleArchive) error {
+	return s.m.C.Articles.Database.C("ArticleArchives").FindId(in.Id).One(out)
+}
+
+func (s *StorageReader) ArticleSummaries(in *types.ObjectIds, out *types.ArticleSummaries) (err error) {
+	docs := []struct {
+		Id      bson.ObjectId `bson:"_id"`
+		Summary []string
+	}{}
+	err = s.m.C.Articles.Find(bson.M{"_id": bson.M{"$in": in.Ids}}).Select(bson.M{"summary": 1}).All(&docs)
+	if err != nil {
+		return
+	}
+	out.Summaries = make(map[string][]string, len(docs))
+	for _, d := range docs {
+		if len(d.Summary) > 0 {
+			out.Summaries[d.Id.Hex()] = d.Summary
+		}
+	}
+	return
+}
+
 func (s *StorageReader) Articles(in *types.MultiQuery, out *types.MultiArticles) (err error) {
 	objectIdify(&in.Query)
 
@@ -62,6 +87,42 @@ func (s *StorageReader) Articles(in *types.MultiQuery, out *types.MultiArticles)
 	return s.m.GetArticles(in.Query, in.Sort, in.Skip, in.Limit, in.Select, &out.Articles)
 }
 
+func (s *StorageReader) DeadArticle(in *types.ObjectId, out *types.DeadArticle) error {
+	return s.m.C.Articles.Database.C("DeadArticles").FindId(in.Id).One(out)
+}
+
+func (s *StorageReader) DeadArticles(in *types.MultiQuery, out *types.MultiDeadArticles) (err error) {
+	objectIdify(&in.Query)
+
+	c := s.m.C.Articles.Database.C("DeadArticles")
+	if out.Total, err = c.Find(in.Query).Count(); err != nil {
+		return
+	}
+	out.Query = *in
+	out.Articles = make([]*types.DeadArticle, 0, in.Limit)
+	q := c.Find(in.Query).Select(in.Select).Skip(in.Skip).Limit(in.Limit)
+	if in.Sort != "" {
+		q = q.Sort(in.Sort)
+	}
+	return q.All(&out.Articles)
+}
+
+func (s *StorageReader) Entities(in *types.MultiQuery, out *types.MultiEntities) (err error) {
+	objectIdify(&in.Query)
+
+	c := s.m.C.Articles.Database.C("Entities")
+	if out.Total, err = c.Find(in.Query).Count(); err != nil {
+		return
+	}
+	out.Query = *in
+	out.Entities = make([]*types.Entity, 0, in.Limit)
+	q := c.Find(in.Query).Select(in.Select).Skip(in.Skip).Limit(in.Limit)
+	if in.Sort != "" {
+		q = q.Sort(in.Sort)
+	}
+	return q.All(&out.Entities)
+}
+
 func (s *StorageReader) Feed(in *types.ObjectId, out *coverage.Feed) error {
 	return s.m.GetFeed(in.Id, out)
 }
@@ -82,8 +143,216 @@ func (s *StorageReader) OldestFeed(in *types.ObjectIds, out *coverage.Feed) erro
 	return s.m.GetOldestFeed(in.Ids, out)
 }
 
-func (s *StorageReader) Publication(in *types.ObjectId, out *coverage.Publication) error {
-	return s.m.GetPublication(in.Id, out)
+// Follows redirects left by Publication.Merge, so merged IDs return the
+// publication they were merged into
+func (s *StorageReader) Publication(in *types.ObjectId, out *coverage.Publication) (err error) {
+	if err = s.m.GetPublication(in.Id, out); err != mgo.ErrNotFound {
+		return
+	}
+	redirect := new(types.PubRedirect)
+	if s.m.C.Articles.Database.C("PublicationRedirects").FindId(in.Id).One(redirect) != nil {
+		return
+	}
+	return s.m.GetPublication(redirect.Target, out)
+}
+
+func (s *StorageReader) PublicationTags(in *types.ObjectId, out *types.PubTags) (err error) {
+	doc := struct{ Tags []string }{}
+	if err = s.m.C.Publications.FindId(in.Id).Select(bson.M{"tags": 1}).One(&doc); err != nil {
+		return
+	}
+	out.Id, out.Tags = in.Id, doc.Tags
+	return
+}
+
+// Raw activity counts; Publication.Activity fills in the gaps
+func (s *StorageReader) PublicationActivity(in *types.ActivityQuery, out *types.PubActivity) (err error) {
+	out.Id = in.Publication
+	out.Since = time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -in.Days+1)
+
+	days := []struct {
+		Id struct {
+			Year, Month, Day int
+		} `bson:"_id"`
+		Count int
+	}{}
+	if err = s.m.C.Articles.Pipe([]bson.M{
+		{"$match": bson.M{"publicationid": in.Publication, "published": bson.M{"$gte": out.Since}}},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"year":  bson.M{"$year": "$published"},
+				"month": bson.M{"$month": "$published"},
+				"day":   bson.M{"$dayOfMonth": "$published"},
+			},
+			"count": bson.M{"$sum": 1},
+		}},
+	}).All(&days); err != nil {
+		return
+	}
+	out.Days = make([]types.DayCount, len(days))
+	for i, d := range days {
+		out.Days[i] = types.DayCount{
+			Date:     time.Date(d.Id.Year, time.Month(d.Id.Month), d.Id.Day, 0, 0, 0, 0, time.UTC),
+			Articles: d.Count,
+		}
+	}
+
+	perFeed := []struct {
+		Id    bson.ObjectId `bson:"_id"`
+		Count int
+	}{}
+	if err = s.m.C.Articles.Pipe([]bson.M{
+		{"$match": bson.M{"publicationid": in.Publication, "added": bson.M{"$gte": out.Since}}},
+		{"$group": bson.M{"_id": "$feedid", "count": bson.M{"$sum": 1}}},
+	}).All(&perFeed); err != nil {
+		return
+	}
+	feedCounts := make(map[bson.ObjectId]int, len(perFeed))
+	added := 0
+	for _, f := range perFeed {
+		feedCounts[f.Id] = f.Count
+		added += f.Count
+	}
+
+	// Articles are saved even when no body was found; they have no words
+	noBody := bson.M{
+		"publicationid":    in.Publication,
+		"added":            bson.M{"$gte": out.Since},
+		"text.words.all.0": bson.M{"$exists": false},
+	}
+	if out.Failed, err = s.m.C.Articles.Find(noBody).Count(); err != nil {
+		return
+	}
+	out.Extracted = added - out.Failed
+
+	// Last article per feed across all time, not only the period
+	lastAdded := []struct {
+		Id   bson.ObjectId `bson:"_id"`
+		Last time.Time
+	}{}
+	if err = s.m.C.Articles.Pipe([]bson.M{
+		{"$match": bson.M{"publicationid": in.Publication}},
+		{"$group": bson.M{"_id": "$feedid", "last": bson.M{"$max": "$added"}}},
+	}).All(&lastAdded); err != nil {
+		return
+	}
+	feedLast := make(map[bson.ObjectId]time.Time, len(lastAdded))
+	for _, f := range lastAdded {
+		feedLast[f.Id] = f.Last
+	}
+
+	feeds := []*coverage.Feed{}
+	if err = s.m.C.Feeds.Find(bson.M{"publicationid": in.Publication}).All(&feeds); err != nil {
+		return
+	}
+	out.Feeds = make([]types.FeedActivity, len(feeds))
+	for i, f := range feeds {
+		out.Feeds[i] = types.FeedActivity{
+			Id:           f.ID,
+			URL:          f.URL,
+			Deleted:      f.Deleted,
+			Articles:     feedCounts[f.ID],
+			LastArticle:  feedLast[f.ID],
+			LastDownload: f.LastDownload,
+		}
+	}
+
+	latest := struct{ Published time.Time }{}
+	err = s.m.C.Articles.Find(bson.M{"publicationid": in.Publication}).Sort("-published").Select(bson.M{"published": 1}).One(&latest)
+	if err != nil && err != mgo.ErrNotFound {
+		return
+	}
+	out.LastArticle = latest.Published
+
+	query := bson.M{"article.publicationid": in.Publication, "added": bson.M{"$gte": out.Since}}
+	out.DeadLettered, err = s.m.C.Articles.Database.C("DeadArticles").Find(query).Count()
+	return
+}
+
+// Counts feeds and articles of each publication alongside the stored
+// counters. With no IDs every publication is counted.
+func (s *StorageReader) PublicationCounts(in *types.ObjectIds, out *types.PubCounts) (err error) {
+	pubQuery := bson.M{}
+	feedMatch := bson.M{"deleted": false}
+	articleMatch := bson.M{}
+	if len(in.Ids) > 0 {
+		pubQuery["_id"] = bson.M{"$in": in.Ids}
+		feedMatch["publicationid"] = bson.M{"$in": in.Ids}
+		articleMatch["publicationid"] = bson.M{"$in": in.Ids}
+	}
+
+	type count struct {
+		Id    bson.ObjectId `bson:"_id"`
+		Count int64
+	}
+	group := bson.M{"$group": bson.M{"_id": "$publicationid", "count": bson.M{"$sum": 1}}}
+
+	feeds := []count{}
+	if err = s.m.C.Feeds.Pipe([]bson.M{{"$match": feedMatch}, group}).All(&feeds); err != nil {
+		return
+	}
+	numFeeds := make(map[bson.ObjectId]int, len(feeds))
+	for _, c := range feeds {
+		numFeeds[c.Id] = int(c.Count)
+	}
+
+	articles := []count{}
+	if err = s.m.C.Articles.Pipe([]bson.M{{"$match": articleMatch}, group}).All(&articles); err != nil {
+		return
+	}
+	numArticles := make(map[bson.ObjectId]int64, len(articles))
+	for _, c := range articles {
+		numArticles[c.Id] = c.Count
+	}
+
+	pubs := []struct {
+		Id          bson.ObjectId `bson:"_id"`
+		Title       string
+		NumFeeds    int
+		NumArticles int64
+	}{}
+	selectFields := bson.M{"title": 1, "numfeeds": 1, "numarticles": 1}
+	if err = s.m.C.Publications.Find(pubQuery).Select(selectFields).All(&pubs); err != nil {
+		return
+	}
+	out.Counts = make([]types.PubCount, len(pubs))
+	for i, p := range pubs {
+		out.Counts[i] = types.PubCount{
+			Id:          p.Id,
+			Title:       p.Title,
+			NumFeeds:    p.NumFeeds,
+			Feeds:       numFeeds[p.Id],
+			NumArticles: p.NumArticles,
+			Articles:    numArticles[p.Id],
+		}
+	}
+	return
+}
+
+func (s *StorageReader) PublicationSettings(in *types.ObjectId, out *types.PubSettings) (err error) {
+	doc := struct{ Settings types.PubSettings }{}
+	if err = s.m.C.Publications.FindId(in.Id).Select(bson.M{"settings": 1}).One(&doc); err != nil {
+		return
+	}
+	*out = doc.Settings
+	out.Id = in.Id
+	return
+}
+
+func (s *StorageReader) PubCollections(in *types.MultiQuery, out *types.MultiPubCollections) (err error) {
+	objectIdify(&in.Query)
+
+	c := s.m.C.Articles.Database.C("PublicationCollections")
+	if out.Total, err = c.Find(in.Query).Count(); err != nil {
+		return
+	}
+	out.Query = *in
+	out.Collections = make([]*types.PubCollection, 0, in.Limit)
+	q := c.Find(in.Query).Select(in.Select).Skip(in.Skip).Limit(in.Limit)
+	if in.Sort != "" {
+		q = q.Sort(in.Sort)
+	}
+	return q.All(&out.Collections)
 }
 
 func (s *StorageReader) Publications(in *types.MultiQuery, out *types.MultiPubs) (err error) {
@@ -97,6 +366,56 @@ func (s *StorageReader) Publications(in *types.MultiQuery, out *types.MultiPubs)
 	return s.m.GetPublications(in.Query, in.Sort, in.Skip, in.Limit, &out.Publications)
 }
 
+func (s *StorageReader) Readership(in *types.ObjectId, out *types.ReadershipHistory) (err error) {
+	out.PublicationId = in.Id
+	return s.m.C.Articles.Database.C("Readership").Find(bson.M{"publicationid": in.Id}).Sort("effective").All(&out.History)
+}
+
+// Readers of each publication at each date, falling back to the publication's
+// NumReaders when it has no history that far back
+func (s *StorageReader) ReadershipAt(in *types.ReadershipLookups, out *types.ReadershipValues) (err error) {
+	pubIds := make([]bson.ObjectId, 0, len(in.Lookups))
+	seen := make(map[bson.ObjectId]bool)
+	for _, l := range in.Lookups {
+		if !seen[l.PublicationId] {
+			seen[l.PublicationId] = true
+			pubIds = append(pubIds, l.PublicationId)
+		}
+	}
+
+	entries := []types.Readership{}
+	query := bson.M{"publicationid": bson.M{"$in": pubIds}}
+	if err = s.m.C.Articles.Database.C("Readership").Find(query).Sort("effective").All(&entries); err != nil {
+		return
+	}
+	history := make(map[bson.ObjectId][]types.Readership, len(pubIds))
+	for _, e := range entries {
+		history[e.PublicationId] = append(history[e.PublicationId], e)
+	}
+
+	pubs := []struct {
+		Id         bson.ObjectId `bson:"_id"`
+		NumReaders int64
+	}{}
+	if err = s.m.C.Publications.Find(bson.M{"_id": bson.M{"$in": pubIds}}).Select(bson.M{"numreaders": 1}).All(&pubs); err != nil {
+		return
+	}
+	current := make(map[bson.ObjectId]int64, len(pubs))
+	for _, p := range pubs {
+		current[p.Id] = p.NumReaders
+	}
+
+	out.Readers = make([]int64, len(in.Lookups))
+	for i, l := range in.Lookups {
+		out.Readers[i] = readersAt(history[l.PublicationId], l.Date, current[l.PublicationId])
+	}
+	return
+}
+
+func (s *StorageReader) ReprocessJob(in *types.ObjectId, out *types.ReprocessJob) error {
+	return s.m.C.Articles.Database.C("ReprocessJobs").FindId(in.Id).One(out)
+}
+
 func (s *StorageReader) Search(in *types.ObjectId, out *coverage.Search) error {
 	return s.m.GetSearch(in.Id, out)
 }
@@ -105,6 +424,27 @@ func (s *StorageReader) GroupSearch(in *types.ObjectId, out *coverage.GroupSearc
 	return s.m.GetGroupSearch(in.Id, out)
 }
 
+// Reports when cancelling the group search was requested, if ever
+func (s *StorageReader) GroupSearchCancel(in *types.ObjectId, out *types.GroupSearchCancel) error {
+	return s.m.C.Search.Database.C("GroupSearch").FindId(in.Id).Select(bson.M{"cancelled": 1}).One(out)
+}
+
+func (s *StorageReader) SocialHistory(in *types.ObjectId, out *types.SocialHistory) (err error) {
+	out.ArticleId = in.Id
+	return s.m.C.Articles.Database.C("SocialStats").Find(bson.M{"articleid": in.Id}).Sort("fetched").All(&out.Snapshots)
+}
+
+// Every snapshot of the articles, oldest first
+func (s *StorageReader) SocialSnapshots(in *types.ObjectIds, out *types.SocialSnapshots) (err error) {
+	query := bson.M{"articleid": bson.M{"$in": in.Ids}}
+	return s.m.C.Articles.Database.C("SocialStats").Find(query).Sort("fetched").All(&out.Snapshots)
+}
+
+// Most recent social stats of the article
+func (s *StorageReader) SocialSnapshot(in *types.ObjectId, out *types.SocialSnapshot) (err error) {
+	return s.m.C.Articles.Database.C("SocialStats").Find(bson.M{"articleid": in.Id}).Sort("-fetched").One(out)
+}
+
 func (s *StorageReader) Stats(in *disgo.NullType, out *mongo.Stats) error {
 	return s.m.GetStats(out)
 }
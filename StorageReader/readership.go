@@ -0,0 +1,19 @@
+package StorageReader
+
+import (
+	"github.com/300brand/coverageservices/types"
+	"sort"
+	"time"
+)
+
+// Readers from the latest entry effective on or before the date. History must
+// be sorted oldest first.
+func readersAt(history []types.Readership, date time.Time, fallback int64) int64 {
+	i := sort.Search(len(history), func(i int) bool {
+		return history[i].Effective.After(date)
+	})
+	if i == 0 {
+		return fallback
+	}
+	return history[i-1].Readers
+}
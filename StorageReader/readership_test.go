@@ -0,0 +1,34 @@
+package StorageReader
+
+import (
+	"github.com/300brand/coverageservices/types"
+	"testing"
+	"time"
+)
+
+func TestReadersAt(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2014, time.January, d, 0, 0, 0, 0, time.UTC) }
+	history := []types.Readership{
+		{Readers: 100, Effective: day(5)},
+		{Readers: 200, Effective: day(10)},
+		{Readers: 300, Effective: day(20)},
+	}
+	for _, test := range []struct {
+		Date   time.Time
+		Expect int64
+	}{
+		{day(1), 50},
+		{day(5), 100},
+		{day(9), 100},
+		{day(10), 200},
+		{day(19), 200},
+		{day(25), 300},
+	} {
+		if got := readersAt(history, test.Date, 50); got != test.Expect {
+			t.Errorf("%s: expected %d, got %d", test.Date.Format("Jan 2"), test.Expect, got)
+		}
+	}
+	if got := readersAt(nil, day(1), 75); got != 75 {
+		t.Errorf("Empty history: expected fallback 75, got %d", got)
+	}
+}
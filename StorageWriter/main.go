@@ -10,7 +10,9 @@ import (
 	"github.com/300brand/disgo"
 	"github.com/300brand/go-toml-config"
 	"github.com/300brand/logger"
+	"labix.org/v2/mgo"
 	"labix.org/v2/mgo/bson"
+	"sort"
 	"time"
 )
 
@@ -75,6 +77,14 @@ func (s *StorageWriter) UpdateGroupSearch(in *coverage.GroupSearch, out *disgo.N
 	return s.m.UpdateGroupSearch(in)
 }
 
+// Marks a group search cancelled; its searches stop at their next chunk and
+// it never gets a Complete time
+func (s *StorageWriter) GroupSearchCancel(in *types.ObjectId, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	return c.Search.Database.C("GroupSearch").UpdateId(in.Id, bson.M{"$set": bson.M{"cancelled": time.Now()}})
+}
+
 func (s *StorageWriter) ArticleQueueAdd(in *coverage.Article, out *disgo.NullType) (err error) {
 	return s.m.ArticleQueueAdd(in)
 }
@@ -84,7 +94,60 @@ func (s *StorageWriter) ArticleQueueNext(in *disgo.NullType, out *coverage.Artic
 }
 
 func (s *StorageWriter) ArticleQueueRemove(in *types.ObjectId, out *disgo.NullType) (err error) {
-	return s.m.ArticleQueueRemove(in.Id)
+	if err = s.m.ArticleQueueRemove(in.Id); err != nil {
+		return
+	}
+	c := s.m.Copy()
+	defer c.Close()
+	// Clear out any failed attempts; most articles never have them
+	if err = c.Articles.Database.C("ArticleAttempts").RemoveId(in.Id); err == mgo.ErrNotFound {
+		err = nil
+	}
+	return
+}
+
+// Records a failed processing attempt for a queued article and returns the
+// running tally
+func (s *StorageWriter) ArticleQueueFail(in *types.ArticleFailure, out *types.ArticleAttempts) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	change := mgo.Change{
+		Update: bson.M{
+			"$inc": bson.M{"attempts": 1},
+			"$set": bson.M{"error": in.Error, "updated": time.Now()},
+		},
+		Upsert:    true,
+		ReturnNew: true,
+	}
+	_, err = c.Articles.Database.C("ArticleAttempts").FindId(in.Id).Apply(change, out)
+	return
+}
+
+// Pushes a queued article back to its new Dequeue time
+func (s *StorageWriter) ArticleQueueDelay(in *coverage.Article, out *disgo.NullType) (err error) {
+	if err = s.m.ArticleQueueRemove(in.ID); err != nil {
+		return
+	}
+	return s.m.ArticleQueueAdd(in)
+}
+
+// Moves an article out of the queue and into the dead-letter collection
+func (s *StorageWriter) ArticleDeadLetter(in *types.DeadArticle, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	in.Id = in.Article.ID
+	in.Added = time.Now()
+	logger.Debug.Printf("StorageWriter.ArticleDeadLetter: [A:%s] %d attempts: %s", in.Id.Hex(), in.Attempts, in.Error)
+	if _, err = c.Articles.Database.C("DeadArticles").UpsertId(in.Id, in); err != nil {
+		return
+	}
+	return s.ArticleQueueRemove(&types.ObjectId{Id: in.Id}, out)
+}
+
+func (s *StorageWriter) DeadArticleRemove(in *types.ObjectId, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	return c.Articles.Database.C("DeadArticles").RemoveId(in.Id)
 }
 
 func (s *StorageWriter) Article(in *coverage.Article, out *coverage.Article) (err error) {
@@ -119,6 +182,61 @@ func (s *StorageWriter) Article(in *coverage.Article, out *coverage.Article) (er
 	return
 }
 
+// Saves an article that was already added, such as after reprocessing. The
+// URL index and publication counters are left alone.
+func (s *StorageWriter) ArticleUpdate(in *coverage.Article, out *coverage.Article) (err error) {
+	start := time.Now()
+	prefix := fmt.Sprintf("StorageWriter.ArticleUpdate: [P:%s] [F:%s] [A:%s] [U:%s]", in.PublicationId.Hex(), in.FeedId.Hex(), in.ID.Hex(), in.URL)
+
+	defer func() {
+		*out = *in
+	}()
+
+	if err = s.m.UpdateArticle(in); err != nil {
+		logger.Error.Printf("%s Error saving article: %s", prefix, err)
+		return
+	}
+	if err := s.e.SaveArticle(in); err != nil {
+		logger.Error.Printf("%s Error saving to ElasticSearch: %s", prefix, err)
+	}
+	s.client.Call("Stats.Duration", types.Stat{Name: "StorageWriter.ArticleUpdate", Duration: time.Since(start)}, disgo.Null)
+	return
+}
+
+func (s *StorageWriter) ArticleArchive(in *types.ArticleArchive, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	in.Added = time.Now()
+	_, err = c.Articles.Database.C("ArticleArchives").UpsertId(in.Id, in)
+	return
+}
+
+func (s *StorageWriter) ArticleExtras(in *types.ArticleExtras, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	return c.Articles.UpdateId(in.Id, bson.M{"$set": in})
+}
+
+func (s *StorageWriter) Entity(in *types.Entity, out *types.Entity) (err error) {
+	defer func() {
+		*out = *in
+	}()
+
+	c := s.m.Copy()
+	defer c.Close()
+	if in.Id == "" {
+		in.Id = bson.NewObjectId()
+	}
+	_, err = c.Articles.Database.C("Entities").UpsertId(in.Id, in)
+	return
+}
+
+func (s *StorageWriter) EntityRemove(in *types.ObjectId, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	return c.Articles.Database.C("Entities").RemoveId(in.Id)
+}
+
 func (s *StorageWriter) Feed(in *coverage.Feed, out *coverage.Feed) (err error) {
 	defer func() {
 		*out = *in
@@ -154,10 +272,526 @@ func (s *StorageWriter) PubIncFeeds(in *types.Inc, out *disgo.NullType) (err err
 	return s.m.PublicationIncFeeds(in.Id, in.Delta)
 }
 
-func (s *StorageWriter) UpdatePublication(in *types.Set, out *disgo.NullType) (err error) {
+// Saves the progress of a reprocess job and returns the stored copy so the
+// runner can see if it has been cancelled
+func (s *StorageWriter) ReprocessJob(in *types.ReprocessJob, out *types.ReprocessJob) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	change := mgo.Change{
+		Update: bson.M{"$set": bson.M{
+			"query":       in.Query,
+			"archived":    in.Archived,
+			"concurrency": in.Concurrency,
+			"state":       in.State,
+			"total":       in.Total,
+			"processed":   in.Processed,
+			"failed":      in.Failed,
+			"failures":    in.Failures,
+			"start":       in.Start,
+			"complete":    in.Complete,
+		}},
+		Upsert:    true,
+		ReturnNew: true,
+	}
+	_, err = c.Articles.Database.C("ReprocessJobs").FindId(in.Id).Apply(change, out)
+	return
+}
+
+func (s *StorageWriter) ReprocessJobCancel(in *types.ObjectId, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	return c.Articles.Database.C("ReprocessJobs").UpdateId(in.Id, bson.M{"$set": bson.M{"cancelled": true}})
+}
+
+// One entry per publication and effective date; saving the same date again
+// replaces the readers
+func (s *StorageWriter) Readership(in *types.Readership, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	selector := bson.M{"publicationid": in.PublicationId, "effective": in.Effective}
+	_, err = c.Articles.Database.C("Readership").Upsert(selector, bson.M{"$set": bson.M{
+		"readers": in.Readers,
+		"added":   in.Added,
+	}})
+	return
+}
+
+func (s *StorageWriter) PubCollection(in *types.PubCollection, out *types.PubCollection) (err error) {
+	defer func() {
+		*out = *in
+	}()
+
+	c := s.m.Copy()
+	defer c.Close()
+	if in.Id == "" {
+		in.Id = bson.NewObjectId()
+	}
+	_, err = c.Articles.Database.C("PublicationCollections").UpsertId(in.Id, in)
+	return
+}
+
+func (s *StorageWriter) PubCollectionRemove(in *types.ObjectId, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	return c.Articles.Database.C("PublicationCollections").RemoveId(in.Id)
+}
+
+// Overwrites the stored counters with the actual counts
+func (s *StorageWriter) PublicationCounts(in *types.PubCount, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	return c.Publications.UpdateId(in.Id, bson.M{"$set": bson.M{
+		"numfeeds":    in.Feeds,
+		"numarticles": in.Articles,
+	}})
+}
+
+// Moves feeds, articles (in ElasticSearch too), saved search references,
+// readership history and tags from each source to the target, replacing each
+// source with a redirect. Publication.Merge validates the IDs first.
+func (s *StorageWriter) PublicationMerge(in *types.PubMerge, out *types.PubMergeResult) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	redirects := c.Articles.Database.C("PublicationRedirects")
+	collections := c.Articles.Database.C("PublicationCollections")
+	readership := c.Articles.Database.C("Readership")
+
+	var info *mgo.ChangeInfo
+	for _, id := range in.Sources {
+		move := bson.M{"$set": bson.M{"publicationid": in.Target}}
+		if info, err = c.Feeds.UpdateAll(bson.M{"publicationid": id}, move); err != nil {
+			return
+		}
+		out.Feeds += info.Updated
+		s.reindexMerged(c, id, in.Target)
+		if info, err = c.Articles.UpdateAll(bson.M{"publicationid": id}, move); err != nil {
+			return
+		}
+		out.Articles += info.Updated
+		if info, err = c.ArticleQueue.UpdateAll(bson.M{"publicationid": id}, move); err != nil {
+			return
+		}
+		out.QueuedArticles += info.Updated
+
+		var searches int
+		if searches, err = replacePubId(c.Search, id, in.Target); err != nil {
+			return
+		}
+		out.Searches += searches
+		if _, err = replacePubId(collections, id, in.Target); err != nil {
+			return
+		}
+		var moved int
+		if moved, err = mergeReadership(readership, id, in.Target); err != nil {
+			return
+		}
+		out.Readership += moved
+		if err = mergeTags(c.Publications, id, in.Target); err != nil {
+			return
+		}
+
+		// Point earlier redirects straight at the new target
+		if _, err = redirects.UpdateAll(bson.M{"target": id}, bson.M{"$set": bson.M{"target": in.Target}}); err != nil {
+			return
+		}
+		if _, err = redirects.UpsertId(id, &types.PubRedirect{Id: id, Target: in.Target, Merged: time.Now()}); err != nil {
+			return
+		}
+		if err = c.Publications.RemoveId(id); err != nil {
+			return
+		}
+		logger.Info.Printf("StorageWriter.PublicationMerge: [P:%s] Merged into %s", id.Hex(), in.Target.Hex())
+	}
+
+	numFeeds, err := c.Feeds.Find(bson.M{"publicationid": in.Target, "deleted": false}).Count()
+	if err != nil {
+		return
+	}
+	numArticles, err := c.Articles.Find(bson.M{"publicationid": in.Target}).Count()
+	if err != nil {
+		return
+	}
+	return c.Publications.UpdateId(in.Target, bson.M{"$set": bson.M{
+		"numfeeds":    numFeeds,
+		"numarticles": numArticles,
+		"updated":     time.Now(),
+	}})
+}
+
+// Saves the source's articles to ElasticSearch under the target ahead of the
+// move in Mongo. Failures are logged; the article stays searchable in Mongo.
+func (s *StorageWriter) reindexMerged(c *mongo.Collections, from, to bson.ObjectId) {
+	a := new(coverage.Article)
+	iter := c.Articles.Find(bson.M{"publicationid": from}).Iter()
+	for iter.Next(a) {
+		a.PublicationId = to
+		if err := s.e.SaveArticle(a); err != nil {
+			logger.Error.Printf("StorageWriter.PublicationMerge: [P:%s] [A:%s] Error re-indexing in ElasticSearch: %s", from.Hex(), a.ID.Hex(), err)
+		}
+		*a = coverage.Article{}
+	}
+	if err := iter.Close(); err != nil {
+		logger.Error.Printf("StorageWriter.PublicationMerge: [P:%s] Error re-indexing in ElasticSearch: %s", from.Hex(), err)
+	}
+}
+
+// Moves the source's readership history to the target. Entries at dates the
+// target already has are dropped so its own figures win.
+func mergeReadership(c *mgo.Collection, from, to bson.ObjectId) (moved int, err error) {
+	target, source := []types.Readership{}, []types.Readership{}
+	if err = c.Find(bson.M{"publicationid": to}).All(&target); err != nil {
+		return
+	}
+	if err = c.Find(bson.M{"publicationid": from}).All(&source); err != nil {
+		return
+	}
+	move, drop := readershipMoves(target, source)
+	if len(move) > 0 {
+		if _, err = c.UpdateAll(bson.M{"_id": bson.M{"$in": move}}, bson.M{"$set": bson.M{"publicationid": to}}); err != nil {
+			return
+		}
+	}
+	if len(drop) > 0 {
+		if _, err = c.RemoveAll(bson.M{"_id": bson.M{"$in": drop}}); err != nil {
+			return
+		}
+	}
+	return len(move), nil
+}
+
+// Splits the source entries into those to move and those the target already
+// has a figure for
+func readershipMoves(target, source []types.Readership) (move, drop []bson.ObjectId) {
+	have := make(map[int64]bool, len(target))
+	for _, r := range target {
+		have[r.Effective.Unix()] = true
+	}
+	for _, r := range source {
+		if have[r.Effective.Unix()] {
+			drop = append(drop, r.Id)
+			continue
+		}
+		move = append(move, r.Id)
+	}
+	return
+}
+
+// Adds the source's tags to the target
+func mergeTags(c *mgo.Collection, from, to bson.ObjectId) (err error) {
+	source := struct{ Tags []string }{}
+	if err = c.FindId(from).Select(bson.M{"tags": 1}).One(&source); err != nil || len(source.Tags) == 0 {
+		return
+	}
+	return c.UpdateId(to, bson.M{"$addToSet": bson.M{"tags": bson.M{"$each": source.Tags}}})
+}
+
+// Swaps one ID for another in the publicationids arrays of a collection
+func replacePubId(c *mgo.Collection, from, to bson.ObjectId) (updated int, err error) {
+	// Can't $addToSet and $pull the same field in one update
+	info, err := c.UpdateAll(bson.M{"publicationids": from}, bson.M{"$addToSet": bson.M{"publicationids": to}})
+	if err != nil {
+		return
+	}
+	if _, err = c.UpdateAll(bson.M{"publicationids": from}, bson.M{"$pull": bson.M{"publicationids": from}}); err != nil {
+		return
+	}
+	return info.Updated, nil
+}
+
+// Hard-deletes publications soft-deleted before the threshold along with
+// their feeds, articles and everything kept about them (see
+// purgeArticleData).
+// Archived HTML no longer referenced by any article is listed in the output
+// for the Article service to delete.
+func (s *StorageWriter) PublicationPurge(in *types.DateThreshold, out *types.PubPurge) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+
+	pubs := []struct {
+		Id bson.ObjectId `bson:"_id"`
+	}{}
+	if err = c.Publications.Find(bson.M{"deleted": bson.M{"$lt": in.Threshold}}).Select(bson.M{"_id": 1}).All(&pubs); err != nil {
+		return
+	}
+	var articles, feeds *mgo.ChangeInfo
+	keys := make(map[string]bool)
+	for _, p := range pubs {
+		if err = s.purgeArticleData(c, p.Id, keys); err != nil {
+			return
+		}
+		if articles, err = c.Articles.RemoveAll(bson.M{"publicationid": p.Id}); err != nil {
+			return
+		}
+		if feeds, err = c.Feeds.RemoveAll(bson.M{"publicationid": p.Id}); err != nil {
+			return
+		}
+		if _, err = c.Articles.Database.C("PublicationCollections").UpdateAll(bson.M{"publicationids": p.Id}, bson.M{"$pull": bson.M{"publicationids": p.Id}}); err != nil {
+			return
+		}
+		if err = c.Publications.RemoveId(p.Id); err != nil {
+			return
+		}
+		out.Articles += articles.Removed
+		out.Feeds += feeds.Removed
+		out.Publications++
+		logger.Info.Printf("StorageWriter.PublicationPurge: [P:%s] Purged %d articles, %d feeds", p.Id.Hex(), articles.Removed, feeds.Removed)
+	}
+	out.ArchiveKeys, err = unreferencedKeys(c, keys)
+	return
+}
+
+// Articles handled per round trip while purging
+const purgeBatch = 1000
+
+// Removes what is kept about a publication and its articles outside of the
+// Articles collection: archive references, attempts, dead letters, social
+// stats and polls, URLs, ElasticSearch documents, readership and redirects.
+// Archive keys of the removed references are added to keys.
+func (s *StorageWriter) purgeArticleData(c *mongo.Collections, pubId bson.ObjectId, keys map[string]bool) (err error) {
+	db := c.Articles.Database
+	ids := make([]bson.ObjectId, 0, purgeBatch)
+	urls := make([]string, 0, purgeBatch)
+	flush := func() (err error) {
+		if len(ids) == 0 {
+			return
+		}
+		archives := []types.ArticleArchive{}
+		if err = db.C("ArticleArchives").Find(bson.M{"_id": bson.M{"$in": ids}}).Select(bson.M{"key": 1, "pages": 1}).All(&archives); err != nil {
+			return
+		}
+		for _, a := range archives {
+			keys[a.Key] = true
+			for _, p := range a.Pages {
+				keys[p.Key] = true
+			}
+		}
+		for _, name := range []string{"ArticleArchives", "ArticleAttempts"} {
+			if _, err = db.C(name).RemoveAll(bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+				return
+			}
+		}
+		for _, name := range []string{"SocialStats", "SocialPolls"} {
+			if _, err = db.C(name).RemoveAll(bson.M{"articleid": bson.M{"$in": ids}}); err != nil {
+				return
+			}
+		}
+		if _, err = c.URLs.RemoveAll(bson.M{"_id": bson.M{"$in": urls}}); err != nil {
+			return
+		}
+		for _, id := range ids {
+			if err := s.e.DeleteArticle(id); err != nil {
+				logger.Error.Printf("StorageWriter.PublicationPurge: [P:%s] [A:%s] Error removing from ElasticSearch: %s", pubId.Hex(), id.Hex(), err)
+			}
+		}
+		ids, urls = ids[:0], urls[:0]
+		return
+	}
+
+	a := struct {
+		Id  bson.ObjectId `bson:"_id"`
+		URL string
+	}{}
+	iter := c.Articles.Find(bson.M{"publicationid": pubId}).Select(bson.M{"_id": 1, "url": 1}).Iter()
+	for iter.Next(&a) {
+		ids, urls = append(ids, a.Id), append(urls, a.URL)
+		if len(ids) < purgeBatch {
+			continue
+		}
+		if err = flush(); err != nil {
+			iter.Close()
+			return
+		}
+	}
+	if err = iter.Close(); err != nil {
+		return
+	}
+	if err = flush(); err != nil {
+		return
+	}
+
+	// Dead letters never made it into Articles, so go by the copy they hold
+	if _, err = db.C("DeadArticles").RemoveAll(bson.M{"article.publicationid": pubId}); err != nil {
+		return
+	}
+	if _, err = db.C("Readership").RemoveAll(bson.M{"publicationid": pubId}); err != nil {
+		return
+	}
+	// Merged publications redirect here and would otherwise lead nowhere
+	_, err = db.C("PublicationRedirects").RemoveAll(bson.M{"$or": []bson.M{{"_id": pubId}, {"target": pubId}}})
+	return
+}
+
+// Returns the keys no archive reference, first page or later, still uses.
+// Identical HTML shares one key, so another article may still need it.
+func unreferencedKeys(c *mongo.Collections, keys map[string]bool) (unused []string, err error) {
+	list := make([]string, 0, len(keys))
+	for k := range keys {
+		list = append(list, k)
+	}
+	archives := c.Articles.Database.C("ArticleArchives")
+	for i := 0; i < len(list); i += purgeBatch {
+		batch := list[i:]
+		if len(batch) > purgeBatch {
+			batch = batch[:purgeBatch]
+		}
+		used := []types.ArticleArchive{}
+		query := bson.M{"$or": []bson.M{
+			{"key": bson.M{"$in": batch}},
+			{"pages.key": bson.M{"$in": batch}},
+		}}
+		if err = archives.Find(query).Select(bson.M{"key": 1, "pages": 1}).All(&used); err != nil {
+			return
+		}
+		for _, a := range used {
+			delete(keys, a.Key)
+			for _, p := range a.Pages {
+				delete(keys, p.Key)
+			}
+		}
+	}
+	for k := range keys {
+		unused = append(unused, k)
+	}
+	sort.Strings(unused)
+	return
+}
+
+// Soft-deletes a publication and the feeds which were not already deleted,
+// and drops its articles from the processing queue. Returns mgo.ErrNotFound
+// if the publication does not exist or is already deleted.
+func (s *StorageWriter) PublicationRemove(in *types.ObjectId, out *types.PubRemoval) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+
+	out.Id = in.Id
+	query := bson.M{"_id": in.Id, "deleted": bson.M{"$exists": false}}
+	if err = c.Publications.Update(query, bson.M{"$set": bson.M{"deleted": time.Now()}}); err != nil {
+		return
+	}
+	// pubdeleted marks the feeds to bring back on restore
+	info, err := c.Feeds.UpdateAll(
+		bson.M{"publicationid": in.Id, "deleted": false},
+		bson.M{"$set": bson.M{"deleted": true, "pubdeleted": true}},
+	)
+	if err != nil {
+		return
+	}
+	out.Feeds = info.Updated
+	if info, err = c.ArticleQueue.RemoveAll(bson.M{"publicationid": in.Id}); err != nil {
+		return
+	}
+	out.QueuedArticles = info.Removed
+	return
+}
+
+// Reverses PublicationRemove. Feeds deleted before the publication stay
+// deleted; dropped queue entries are not restored.
+func (s *StorageWriter) PublicationRestore(in *types.ObjectId, out *types.PubRemoval) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+
+	out.Id = in.Id
+	query := bson.M{"_id": in.Id, "deleted": bson.M{"$exists": true}}
+	if err = c.Publications.Update(query, bson.M{"$unset": bson.M{"deleted": 1}}); err != nil {
+		return
+	}
+	info, err := c.Feeds.UpdateAll(
+		bson.M{"publicationid": in.Id, "pubdeleted": true},
+		bson.M{"$set": bson.M{"deleted": false}, "$unset": bson.M{"pubdeleted": 1}},
+	)
+	if err != nil {
+		return
+	}
+	out.Feeds = info.Updated
+	return
+}
+
+// Tags are normalized by Publication.Tag
+func (s *StorageWriter) PublicationTags(in *types.PubTagChange, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	// Can't $addToSet and $pull the same field in one update
+	if len(in.Add) > 0 {
+		if err = c.Publications.UpdateId(in.Id, bson.M{"$addToSet": bson.M{"tags": bson.M{"$each": in.Add}}}); err != nil {
+			return
+		}
+	}
+	if len(in.Remove) > 0 {
+		if err = c.Publications.UpdateId(in.Id, bson.M{"$pull": bson.M{"tags": bson.M{"$in": in.Remove}}}); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Schedules a poll; scheduling the same article and offset again moves it
+func (s *StorageWriter) SocialPollAdd(in *types.SocialPoll, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	selector := bson.M{"articleid": in.ArticleId, "offset": in.Offset}
+	_, err = c.Articles.Database.C("SocialPolls").Upsert(selector, bson.M{"$set": bson.M{
+		"url":      in.URL,
+		"due":      in.Due,
+		"attempts": in.Attempts,
+	}})
+	return
+}
+
+// Removes and returns the earliest poll due by the threshold. Leaves out
+// empty when nothing is due
+func (s *StorageWriter) SocialPollNext(in *types.DateThreshold, out *types.SocialPoll) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	query := c.Articles.Database.C("SocialPolls").Find(bson.M{"due": bson.M{"$lte": in.Threshold}}).Sort("due")
+	if _, err = query.Apply(mgo.Change{Remove: true}, out); err == mgo.ErrNotFound {
+		err = nil
+	}
+	return
+}
+
+func (s *StorageWriter) SocialSnapshot(in *types.SocialSnapshot, out *disgo.NullType) (err error) {
+	c := s.m.Copy()
+	defer c.Close()
+	if in.Id == "" {
+		in.Id = bson.NewObjectId()
+	}
+	return c.Articles.Database.C("SocialStats").Insert(in)
+}
+
+// Applies changes validated by Publication.Update
+func (s *StorageWriter) UpdatePublication(in *types.PubChanges, out *disgo.NullType) (err error) {
+	fields := bson.M{"updated": time.Now()}
+	if in.Title != nil {
+		fields["title"] = *in.Title
+	}
+	if in.URL != nil {
+		fields["url"] = *in.URL
+	}
+	if in.NumReaders != nil {
+		fields["numreaders"] = *in.NumReaders
+	}
+	if in.AuthorXPaths != nil {
+		fields["xpaths.author"] = *in.AuthorXPaths
+	}
+	if in.BodyXPaths != nil {
+		fields["xpaths.body"] = *in.BodyXPaths
+	}
+	if in.DateXPaths != nil {
+		fields["xpaths.date"] = *in.DateXPaths
+	}
+	if in.TitleXPaths != nil {
+		fields["xpaths.title"] = *in.TitleXPaths
+	}
+	if in.MaxFileSize != nil {
+		fields["settings.maxfilesize"] = *in.MaxFileSize
+	}
+	if in.NextPageXPaths != nil {
+		fields["settings.nextpage"] = *in.NextPageXPaths
+	}
+
 	c := s.m.Copy()
 	defer c.Close()
-	set := bson.M{"$set": bson.M{in.Key: in.Value}}
+	set := bson.M{"$set": fields}
 	logger.Debug.Printf("StorageWriter.UpdatePublication: [P:%s] %+v", in.Id.Hex(), set)
 	return c.Publications.UpdateId(in.Id, set)
 }
@@ -0,0 +1,30 @@
+package StorageWriter
+
+import (
+	"github.com/300brand/coverageservices/types"
+	"labix.org/v2/mgo/bson"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadershipMoves(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2014, 3, d, 0, 0, 0, 0, time.UTC) }
+	a, b, c := bson.NewObjectId(), bson.NewObjectId(), bson.NewObjectId()
+	target := []types.Readership{
+		{Id: bson.NewObjectId(), Effective: day(1), Readers: 100},
+		{Id: bson.NewObjectId(), Effective: day(10), Readers: 150},
+	}
+	source := []types.Readership{
+		{Id: a, Effective: day(1), Readers: 90},
+		{Id: b, Effective: day(5), Readers: 95},
+		{Id: c, Effective: day(20), Readers: 120},
+	}
+	move, drop := readershipMoves(target, source)
+	if expect := []bson.ObjectId{b, c}; !reflect.DeepEqual(move, expect) {
+		t.Errorf("Move: Expected %v, got %v", expect, move)
+	}
+	if expect := []bson.ObjectId{a}; !reflect.DeepEqual(drop, expect) {
+		t.Errorf("Drop: Expected %v, got %v", expect, drop)
+	}
+}
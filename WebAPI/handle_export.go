@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/300brand/coverage"
+	"github.com/300brand/coverageservices/summary"
 	"github.com/300brand/coverageservices/types"
 	"github.com/300brand/logger"
 	"io"
@@ -32,6 +33,9 @@ func (s *Service) HandleExport(w http.ResponseWriter, r *http.Request) {
 	if str := r.URL.Query().Get("limit"); str != "" {
 		limit, _ = strconv.Atoi(str)
 	}
+	// summary=query builds each article summary around the search terms
+	// instead of using the one stored when the article was processed
+	focused := r.URL.Query().Get("summary") == "query"
 
 	searchId := bson.ObjectIdHex(qSearchId)
 	logger.Debug.Printf("Exporting results for %s", searchId)
@@ -42,13 +46,13 @@ func (s *Service) HandleExport(w http.ResponseWriter, r *http.Request) {
 		groupSearch.SearchIds = []bson.ObjectId{searchId}
 	}
 
-	filename := filepath.Join(os.TempDir(), "dbs", fmt.Sprintf("%s-%d.sqlite3", qSearchId, limit))
+	filename := filepath.Join(os.TempDir(), "dbs", fmt.Sprintf("%s-%d-%t.sqlite3", qSearchId, limit, focused))
 	os.MkdirAll(filepath.Dir(filename), 0755)
 	f, err := os.Open(filename)
 	if os.IsNotExist(err) {
 		// Generate database:
 		for _, id := range groupSearch.SearchIds {
-			if err := s.generateExport(id, filename, limit); err != nil {
+			if err := s.generateExport(id, filename, limit, focused); err != nil {
 				logger.Error.Printf("[S:%s] HandleExport: %s", qSearchId, err)
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -64,17 +68,20 @@ func (s *Service) HandleExport(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (s *Service) generateExport(id bson.ObjectId, filename string, limit int) (err error) {
+func (s *Service) generateExport(id bson.ObjectId, filename string, limit int, focused bool) (err error) {
 	var (
 		aInsert    *sql.Stmt
 		pInsert    *sql.Stmt
 		sInsert    *sql.Stmt
+		tInsert    *sql.Stmt
 		sqlCreates = []string{
 			`CREATE TABLE IF NOT EXISTS Searches (
 				search_id CHAR(24) PRIMARY KEY,
 				query     TEXT,
 				label     TEXT,
-				duration  INTEGER
+				duration  INTEGER,
+				sentiment REAL,
+				reach     INTEGER
 			)`,
 			`CREATE TABLE IF NOT EXISTS Articles (
 				article_id     CHAR(24),
@@ -86,8 +93,17 @@ func (s *Service) generateExport(id bson.ObjectId, filename string, limit int) (
 				url            TEXT,
 				body           TEXT,
 				published      DATETIME,
+				sentiment      REAL,
+				summary        TEXT,
+				readership     INTEGER,
 				PRIMARY KEY    (article_id, search_id)
 			)`,
+			`CREATE TABLE IF NOT EXISTS Sentences (
+				article_id CHAR(24),
+				search_id  CHAR(24),
+				sentence   TEXT,
+				sentiment  REAL
+			)`,
 			`CREATE TABLE IF NOT EXISTS Pubs (
 				publication_id CHAR(24) PRIMARY KEY,
 				title          TEXT,
@@ -122,16 +138,21 @@ func (s *Service) generateExport(id bson.ObjectId, filename string, limit int) (
 		}
 	}
 
-	if sInsert, err = tx.Prepare("INSERT INTO Searches VALUES (?, ?, ?, ?)"); err != nil {
+	if sInsert, err = tx.Prepare("INSERT INTO Searches VALUES (?, ?, ?, ?, ?, ?)"); err != nil {
 		return
 	}
 	defer sInsert.Close()
 
-	if aInsert, err = tx.Prepare("INSERT INTO Articles VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"); err != nil {
+	if aInsert, err = tx.Prepare("INSERT INTO Articles VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"); err != nil {
 		return
 	}
 	defer aInsert.Close()
 
+	if tInsert, err = tx.Prepare("INSERT INTO Sentences VALUES (?, ?, ?, ?)"); err != nil {
+		return
+	}
+	defer tInsert.Close()
+
 	if pInsert, err = tx.Prepare("INSERT OR IGNORE INTO Pubs VALUES (?, ?, ?)"); err != nil {
 		return
 	}
@@ -143,13 +164,17 @@ func (s *Service) generateExport(id bson.ObjectId, filename string, limit int) (
 		search.Complete = &t
 	}
 
-	if _, err = sInsert.Exec(
-		search.Id.Hex(),
-		search.Q,
-		search.Label,
-		(*search.Complete).Sub(search.Start).Nanoseconds(),
-	); err != nil {
-		return
+	// Searches from before sentiment scoring export NULLs
+	var searchSentiment interface{}
+	resultSentiment := make(map[bson.ObjectId]types.ResultSentiment)
+	sentiment := new(types.SearchSentiment)
+	if err := s.client.Call("Search.Sentiment", types.ObjectId{id}, sentiment); err != nil {
+		logger.Warn.Printf("[S:%s] No sentiment: %s", search.Id.Hex(), err)
+	} else {
+		searchSentiment = sentiment.Summary.Mean
+		for _, r := range sentiment.Results {
+			resultSentiment[r.Id] = r
+		}
 	}
 
 	pubMap := make(map[bson.ObjectId]bool, len(search.Articles))
@@ -167,9 +192,40 @@ func (s *Service) generateExport(id bson.ObjectId, filename string, limit int) (
 	if err = s.client.Call("StorageReader.Articles", aQuery, articles); err != nil {
 		return
 	}
-	for _, a := range articles.Articles {
+	// Readership as of each article's publish date
+	lookups := &types.ReadershipLookups{Lookups: make([]types.ReadershipLookup, len(articles.Articles))}
+	for i, a := range articles.Articles {
+		lookups.Lookups[i] = types.ReadershipLookup{PublicationId: a.PublicationId, Date: a.Published}
+	}
+	readership := new(types.ReadershipValues)
+	if err = s.client.Call("StorageReader.ReadershipAt", lookups, readership); err != nil {
+		return
+	}
+	var reach int64
+
+	summaries := new(types.ArticleSummaries)
+	if !focused {
+		if err = s.client.Call("StorageReader.ArticleSummaries", types.ObjectIds{search.Articles}, summaries); err != nil {
+			return
+		}
+	}
+	for i, a := range articles.Articles {
 		logger.Info.Printf("%s", a.ID.Hex())
+		reach += readership.Readers[i]
 		pubMap[a.PublicationId] = true
+		var score interface{}
+		r, scored := resultSentiment[a.ID]
+		if scored {
+			score = r.Sentiment
+		}
+		sentences, ok := summaries.Summaries[a.ID.Hex()]
+		switch {
+		case focused:
+			sentences = summary.Focused(string(a.Text.Body.Text), sentiment.Terms, *cfgExportSummary)
+		case !ok:
+			// Articles processed before summaries existed get one on the fly
+			sentences = summary.Rank(string(a.Text.Body.Text), *cfgExportSummary)
+		}
 		if _, err = aInsert.Exec(
 			a.ID.Hex(),
 			a.FeedId.Hex(),
@@ -180,9 +236,33 @@ func (s *Service) generateExport(id bson.ObjectId, filename string, limit int) (
 			a.URL,
 			string(a.Text.Body.Text),
 			a.Published,
+			score,
+			strings.Join(sentences, " "),
+			readership.Readers[i],
 		); err != nil {
 			return
 		}
+		for _, sentence := range r.Sentences {
+			if _, err = tInsert.Exec(
+				a.ID.Hex(),
+				search.Id.Hex(),
+				sentence.Text,
+				sentence.Score,
+			); err != nil {
+				return
+			}
+		}
+	}
+
+	if _, err = sInsert.Exec(
+		search.Id.Hex(),
+		search.Q,
+		search.Label,
+		(*search.Complete).Sub(search.Start).Nanoseconds(),
+		searchSentiment,
+		reach,
+	); err != nil {
+		return
 	}
 
 	pubIds := make([]bson.ObjectId, 0, len(pubMap))
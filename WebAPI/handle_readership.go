@@ -0,0 +1,95 @@
+package WebAPI
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/300brand/coverageservices/types"
+	"github.com/300brand/logger"
+	"io"
+	"labix.org/v2/mgo/bson"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Accepts a CSV of publication_id,readers[,effective] rows, with effective as
+// YYYY-MM-DD, and responds with types.ReadershipResults as JSON. Errors refer
+// to CSV line numbers. A header row is skipped.
+func (s *Service) HandleReadership(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "POST a CSV of publication_id,readers,effective", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var (
+		updates = new(types.ReadershipUpdates)
+		results = new(types.ReadershipResults)
+		lines   []int // CSV line of each update
+		parse   []types.ReadershipError
+	)
+	reader := csv.NewReader(r.Body)
+	reader.FieldsPerRecord = -1
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if line == 1 && strings.TrimSpace(record[0]) == "publication_id" {
+			continue
+		}
+		u, err := parseReadership(record)
+		if err != nil {
+			parse = append(parse, types.ReadershipError{Index: line, Error: err.Error()})
+			continue
+		}
+		updates.Updates = append(updates.Updates, u)
+		lines = append(lines, line)
+	}
+
+	if len(updates.Updates) > 0 {
+		if err := s.client.Call("Publication.SetReadership", updates, results); err != nil {
+			logger.Error.Printf("HandleReadership: %s", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	for i := range results.Errors {
+		results.Errors[i].Index = lines[results.Errors[i].Index]
+	}
+	results.Errors = append(parse, results.Errors...)
+
+	w.Header().Add("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		logger.Error.Printf("HandleReadership: %s", err)
+	}
+}
+
+func parseReadership(record []string) (u types.ReadershipUpdate, err error) {
+	if len(record) < 2 || len(record) > 3 {
+		err = fmt.Errorf("Expected 2 or 3 columns, got %d", len(record))
+		return
+	}
+	id := strings.TrimSpace(record[0])
+	if !bson.IsObjectIdHex(id) {
+		err = fmt.Errorf("Invalid publication_id %q", id)
+		return
+	}
+	u.PublicationId = bson.ObjectIdHex(id)
+	if u.Readers, err = strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64); err != nil {
+		err = fmt.Errorf("Invalid readers %q", record[1])
+		return
+	}
+	if len(record) == 3 && strings.TrimSpace(record[2]) != "" {
+		if u.Effective, err = time.Parse("2006-01-02", strings.TrimSpace(record[2])); err != nil {
+			err = fmt.Errorf("Invalid effective date %q; expected YYYY-MM-DD", record[2])
+			return
+		}
+	}
+	return
+}
@@ -1,8 +1,9 @@
 package WebAPI
 
 import (
+	"fmt"
 	"github.com/300brand/coverage"
-	"github.com/300brand/coverage/social"
+	"github.com/300brand/coverageservices/entity"
 	"github.com/300brand/coverageservices/service"
 	"github.com/300brand/coverageservices/types"
 	"github.com/300brand/disgo"
@@ -13,6 +14,7 @@ import (
 	"github.com/gorilla/rpc/json"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -23,6 +25,8 @@ type Service struct {
 type logWriter struct{}
 
 type RPCArticle struct{ s *Service }
+type RPCCollection struct{ s *Service }
+type RPCEntity struct{ s *Service }
 type RPCFeed struct{ s *Service }
 type RPCManager struct{ s *Service }
 type RPCPublication struct{ s *Service }
@@ -33,6 +37,8 @@ var (
 	_ service.Service = new(Service)
 
 	cfgHttpListen = config.String("WebAPI.httplisten", ":8080")
+	// Sentences per article summary built during export
+	cfgExportSummary = config.Int("WebAPI.export.summary", 3)
 
 	jsonrpc  = rpc.NewServer()
 	cmdOnce  = &types.ClockCommand{Command: "once"}
@@ -57,6 +63,8 @@ func (s *Service) Start(client *disgo.Client) (err error) {
 	s.client = client
 
 	jsonrpc.RegisterService(&RPCArticle{s}, "Article")
+	jsonrpc.RegisterService(&RPCCollection{s}, "Collection")
+	jsonrpc.RegisterService(&RPCEntity{s}, "Entity")
 	jsonrpc.RegisterService(&RPCFeed{s}, "Feed")
 	jsonrpc.RegisterService(&RPCManager{s}, "Manager")
 	jsonrpc.RegisterService(&RPCPublication{s}, "Publication")
@@ -82,6 +90,7 @@ func (s *Service) StartRPC() (err error) {
 		w := new(logWriter)
 		http.Handle("/rpc", handlers.LoggingHandler(w, jsonrpc))
 		http.HandleFunc("/exportSearch/", s.HandleExport)
+		http.HandleFunc("/importReadership", s.HandleReadership)
 		logger.Error.Fatal(http.Serve(l, nil))
 	}(listener)
 
@@ -93,16 +102,70 @@ func (s *Service) NOOP(in, out *disgo.NullType) (err error) { return }
 
 // RPC Funcs
 
+func (m *RPCArticle) DeadLetter(r *http.Request, in *types.ObjectId, out *types.DeadArticle) (err error) {
+	return m.s.client.Call("StorageReader.DeadArticle", in, out)
+}
+
+func (m *RPCArticle) DeadLetters(r *http.Request, in *types.MultiQuery, out *types.MultiDeadArticles) (err error) {
+	return m.s.client.Call("StorageReader.DeadArticles", in, out)
+}
+
+func (m *RPCArticle) Drop(r *http.Request, in *types.ObjectId, out *disgo.NullType) (err error) {
+	return m.s.client.Call("StorageWriter.DeadArticleRemove", in, out)
+}
+
 func (m *RPCArticle) Get(r *http.Request, in *types.ObjectId, out *coverage.Article) (err error) {
 	return m.s.client.Call("StorageReader.Article", in, out)
 }
 
-func (m *RPCArticle) Reprocess(r *http.Request, in *types.ObjectId, out *disgo.NullType) (err error) {
-	a := new(coverage.Article)
-	if err = m.Get(r, in, a); err != nil {
-		return
+func (m *RPCArticle) Reprocess(r *http.Request, in *types.Reprocess, out *disgo.NullType) (err error) {
+	return m.s.client.Call("Article.Reprocess", in, new(disgo.NullType))
+}
+
+func (m *RPCArticle) ReprocessAll(r *http.Request, in *types.ReprocessAll, out *types.ReprocessJob) (err error) {
+	return m.s.client.Call("Article.ReprocessAll", in, out)
+}
+
+func (m *RPCArticle) ReprocessCancel(r *http.Request, in *types.ObjectId, out *disgo.NullType) (err error) {
+	return m.s.client.Call("StorageWriter.ReprocessJobCancel", in, out)
+}
+
+func (m *RPCArticle) ReprocessJob(r *http.Request, in *types.ObjectId, out *types.ReprocessJob) (err error) {
+	return m.s.client.Call("StorageReader.ReprocessJob", in, out)
+}
+
+func (m *RPCArticle) Requeue(r *http.Request, in *types.ObjectId, out *disgo.NullType) (err error) {
+	return m.s.client.Call("Article.Requeue", in, out)
+}
+
+func (m *RPCCollection) GetAll(r *http.Request, in *types.MultiQuery, out *types.MultiPubCollections) (err error) {
+	return m.s.client.Call("StorageReader.PubCollections", in, out)
+}
+
+func (m *RPCCollection) Remove(r *http.Request, in *types.ObjectId, out *disgo.NullType) (err error) {
+	return m.s.client.Call("StorageWriter.PubCollectionRemove", in, out)
+}
+
+func (m *RPCCollection) Save(r *http.Request, in *types.PubCollection, out *types.PubCollection) (err error) {
+	return m.s.client.Call("Publication.SaveCollection", in, out)
+}
+
+func (m *RPCEntity) GetAll(r *http.Request, in *types.MultiQuery, out *types.MultiEntities) (err error) {
+	return m.s.client.Call("StorageReader.Entities", in, out)
+}
+
+func (m *RPCEntity) Remove(r *http.Request, in *types.ObjectId, out *disgo.NullType) (err error) {
+	return m.s.client.Call("StorageWriter.EntityRemove", in, out)
+}
+
+func (m *RPCEntity) Save(r *http.Request, in *types.Entity, out *types.Entity) (err error) {
+	if strings.TrimSpace(in.Name) == "" {
+		return fmt.Errorf("Entity name cannot be empty")
 	}
-	return m.s.client.Call("Article.Process", a, new(disgo.NullType))
+	if !entity.ValidType(in.Type) {
+		return fmt.Errorf("Invalid entity type %q; expected %s, %s or %s", in.Type, entity.Organization, entity.Person, entity.Place)
+	}
+	return m.s.client.Call("StorageWriter.Entity", in, out)
 }
 
 func (m *RPCFeed) Add(r *http.Request, in *types.NewFeed, out *coverage.Feed) (err error) {
@@ -134,10 +197,18 @@ func (m *RPCManager) StopFeeds(r *http.Request, in *disgo.NullType, out *disgo.N
 	return m.s.client.Call("Manager.FeedProcessor", cmdStop, disgo.Null)
 }
 
+func (m *RPCPublication) Activity(r *http.Request, in *types.ActivityQuery, out *types.PubActivity) (err error) {
+	return m.s.client.Call("Publication.Activity", in, out)
+}
+
 func (m *RPCPublication) Add(r *http.Request, in *types.Pub, out *coverage.Publication) (err error) {
 	return m.s.client.Call("Publication.Add", in, out)
 }
 
+func (m *RPCPublication) AddAll(r *http.Request, in *types.PubImport, out *types.PubImportResults) (err error) {
+	return m.s.client.Call("Publication.AddAll", in, out)
+}
+
 func (m *RPCPublication) Get(r *http.Request, in *types.ObjectId, out *coverage.Publication) (err error) {
 	return m.s.client.Call("StorageReader.Publication", in, out)
 }
@@ -146,26 +217,123 @@ func (m *RPCPublication) GetAll(r *http.Request, in *types.MultiQuery, out *type
 	return m.s.client.Call("StorageReader.Publications", in, out)
 }
 
+func (m *RPCPublication) GetSettings(r *http.Request, in *types.ObjectId, out *types.PubSettings) (err error) {
+	return m.s.client.Call("StorageReader.PublicationSettings", in, out)
+}
+
+func (m *RPCPublication) GetTags(r *http.Request, in *types.ObjectId, out *types.PubTags) (err error) {
+	return m.s.client.Call("StorageReader.PublicationTags", in, out)
+}
+
+func (m *RPCPublication) Merge(r *http.Request, in *types.PubMerge, out *types.PubMergeResult) (err error) {
+	return m.s.client.Call("Publication.Merge", in, out)
+}
+
+func (m *RPCPublication) Purge(r *http.Request, in *disgo.NullType, out *types.PubPurge) (err error) {
+	return m.s.client.Call("Publication.Purge", in, out)
+}
+
+func (m *RPCPublication) Readership(r *http.Request, in *types.ObjectId, out *types.ReadershipHistory) (err error) {
+	return m.s.client.Call("StorageReader.Readership", in, out)
+}
+
+func (m *RPCPublication) Reconcile(r *http.Request, in *types.PubReconcile, out *types.PubReconcileResults) (err error) {
+	return m.s.client.Call("Publication.Reconcile", in, out)
+}
+
+func (m *RPCPublication) Remove(r *http.Request, in *types.ObjectId, out *types.PubRemoval) (err error) {
+	return m.s.client.Call("Publication.Remove", in, out)
+}
+
+func (m *RPCPublication) Restore(r *http.Request, in *types.ObjectId, out *types.PubRemoval) (err error) {
+	return m.s.client.Call("Publication.Restore", in, out)
+}
+
+// Single-field form of Update
 func (m *RPCPublication) Set(r *http.Request, in *types.Set, out *disgo.NullType) (err error) {
-	return m.s.client.Call("StorageWriter.UpdatePublication", in, out)
+	update := &types.PubUpdate{
+		Id:     in.Id,
+		Fields: map[string]interface{}{in.Key: in.Value},
+	}
+	return m.s.client.Call("Publication.Update", update, out)
+}
+
+func (m *RPCPublication) SetReadership(r *http.Request, in *types.ReadershipUpdates, out *types.ReadershipResults) (err error) {
+	return m.s.client.Call("Publication.SetReadership", in, out)
+}
+
+func (m *RPCPublication) SuggestXPaths(r *http.Request, in *types.XPathSample, out *types.XPathSuggestions) (err error) {
+	return m.s.client.Call("Publication.SuggestXPaths", in, out)
+}
+
+func (m *RPCPublication) Tag(r *http.Request, in *types.PubTagChange, out *disgo.NullType) (err error) {
+	return m.s.client.Call("Publication.Tag", in, out)
+}
+
+func (m *RPCPublication) Update(r *http.Request, in *types.PubUpdate, out *disgo.NullType) (err error) {
+	return m.s.client.Call("Publication.Update", in, out)
 }
 
 func (m *RPCPublication) View(r *http.Request, in *types.ViewPubQuery, out *types.ViewPub) (err error) {
 	return m.s.client.Call("Publication.View", in, out)
 }
 
+func (m *RPCSearch) Cancel(r *http.Request, in *types.ObjectId, out *disgo.NullType) (err error) {
+	return m.s.client.Call("Search.Cancel", in, out)
+}
+
 func (m *RPCSearch) Group(r *http.Request, in *types.GroupQuery, out *types.SearchQueryResponse) (err error) {
 	return m.s.client.Call("Search.GroupSearch", in, out)
 }
 
+func (m *RPCSearch) GroupSentiment(r *http.Request, in *types.ObjectId, out *types.GroupSentiment) (err error) {
+	return m.s.client.Call("Search.GroupSentiment", in, out)
+}
+
+func (m *RPCSearch) Results(r *http.Request, in *types.SearchResultsQuery, out *types.SearchResults) (err error) {
+	return m.s.client.Call("Search.Results", in, out)
+}
+
 func (m *RPCSearch) Search(r *http.Request, in *types.SearchQuery, out *types.SearchQueryResponse) (err error) {
 	return m.s.client.Call("Search.Search", in, out)
 }
 
-func (m *RPCSocial) Article(r *http.Request, in *types.ObjectId, out *social.Stats) (err error) {
+func (m *RPCSearch) Sentiment(r *http.Request, in *types.ObjectId, out *types.SearchSentiment) (err error) {
+	return m.s.client.Call("Search.Sentiment", in, out)
+}
+
+func (m *RPCSearch) Status(r *http.Request, in *types.ObjectId, out *types.SearchStatus) (err error) {
+	return m.s.client.Call("Search.Status", in, out)
+}
+
+func (m *RPCSearch) Social(r *http.Request, in *types.ObjectId, out *disgo.NullType) (err error) {
+	return m.s.client.Call("Search.Social", in, out)
+}
+
+func (m *RPCSearch) SocialCancel(r *http.Request, in *types.ObjectId, out *disgo.NullType) (err error) {
+	return m.s.client.Call("Search.SocialCancel", in, out)
+}
+
+func (m *RPCSearch) SocialProgress(r *http.Request, in *types.ObjectId, out *types.SocialProgress) (err error) {
+	return m.s.client.Call("Search.SocialProgress", in, out)
+}
+
+func (m *RPCSearch) Validate(r *http.Request, in *types.SearchValidation, out *types.SearchValidationResult) (err error) {
+	return m.s.client.Call("Search.Validate", in, out)
+}
+
+func (m *RPCSocial) Article(r *http.Request, in *types.ObjectId, out *types.SocialStats) (err error) {
 	a := new(coverage.Article)
 	if err = m.s.client.Call("StorageReader.Article", in, a); err != nil {
 		return err
 	}
 	return m.s.client.Call("Social.Article", a, out)
 }
+
+func (m *RPCSocial) History(r *http.Request, in *types.ObjectId, out *types.SocialHistory) (err error) {
+	return m.s.client.Call("Social.History", in, out)
+}
+
+func (m *RPCSocial) Search(r *http.Request, in *types.ObjectId, out *types.SocialSeries) (err error) {
+	return m.s.client.Call("Social.Search", in, out)
+}
@@ -0,0 +1,54 @@
+package archive
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+)
+
+// Store keeps raw documents addressed by the hash of their content. Storing
+// the same document twice yields the same key and only one copy, so callers
+// must make sure nothing else refers to a key before deleting it.
+type Store interface {
+	Delete(key string) error
+	Get(key string) ([]byte, error)
+	Put(b []byte) (key string, err error)
+}
+
+// Opener prepares a Store from a backend-specific location (directory, URL,
+// etc.)
+type Opener func(location string) (Store, error)
+
+var backends = make(map[string]Opener)
+
+func Register(name string, open Opener) {
+	if open == nil {
+		panic("archive: Register opener is nil")
+	}
+	if _, dup := backends[name]; dup {
+		panic("archive: Register called twice for " + name)
+	}
+	backends[name] = open
+}
+
+func Open(backend, location string) (Store, error) {
+	open, ok := backends[backend]
+	if !ok {
+		return nil, fmt.Errorf("archive: Unknown backend %q", backend)
+	}
+	return open(location)
+}
+
+// Key returns the content address for b
+func Key(b []byte) string {
+	sum := sha1.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func validKey(key string) bool {
+	if len(key) != sha1.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
@@ -0,0 +1,100 @@
+package archive
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Local stores gzipped documents on the filesystem, fanned out into
+// subdirectories by the first bytes of the key:
+//
+//	<Dir>/ab/cd/abcd...ef.gz
+type Local struct {
+	Dir string
+}
+
+var _ Store = new(Local)
+
+func init() {
+	Register("local", func(location string) (Store, error) {
+		return NewLocal(location)
+	})
+}
+
+func NewLocal(dir string) (l *Local, err error) {
+	if dir == "" {
+		return nil, fmt.Errorf("archive: No directory specified")
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	return &Local{Dir: dir}, nil
+}
+
+// Deleting a key which is not stored is not an error
+func (l *Local) Delete(key string) (err error) {
+	if !validKey(key) {
+		return fmt.Errorf("archive: Invalid key %q", key)
+	}
+	if err = os.Remove(l.path(key)); os.IsNotExist(err) {
+		err = nil
+	}
+	return
+}
+
+func (l *Local) Get(key string) (b []byte, err error) {
+	if !validKey(key) {
+		return nil, fmt.Errorf("archive: Invalid key %q", key)
+	}
+	f, err := os.Open(l.path(key))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+func (l *Local) Put(b []byte) (key string, err error) {
+	key = Key(b)
+	filename := l.path(key)
+	// Content-addressed, so an existing file already holds these bytes
+	if _, err = os.Stat(filename); err == nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return
+	}
+	// Write to a temp file and move into place so readers never see a
+	// partial document
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), key)
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	w := gzip.NewWriter(tmp)
+	if _, err = w.Write(b); err != nil {
+		tmp.Close()
+		return
+	}
+	if err = w.Close(); err != nil {
+		tmp.Close()
+		return
+	}
+	if err = tmp.Close(); err != nil {
+		return
+	}
+	err = os.Rename(tmp.Name(), filename)
+	return
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.Dir, key[0:2], key[2:4], key+".gz")
+}
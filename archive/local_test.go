@@ -0,0 +1,65 @@
+package archive
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestLocalRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := Open("local", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	html := []byte("<html><body><p>Hello</p></body></html>")
+	key, err := store.Put(html)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != Key(html) {
+		t.Errorf("Key mismatch %s != %s", key, Key(html))
+	}
+
+	// Second put of the same content should be a no-op with the same key
+	if key2, err := store.Put(html); err != nil || key2 != key {
+		t.Errorf("Second put: %s %v", key2, err)
+	}
+
+	b, err := store.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, html) {
+		t.Errorf("Content mismatch: %s", b)
+	}
+
+	if err = store.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.Get(key); err == nil {
+		t.Error("Get succeeded after Delete")
+	}
+	if err = store.Delete(key); err != nil {
+		t.Errorf("Deleting a missing key: %s", err)
+	}
+}
+
+func TestLocalInvalidKey(t *testing.T) {
+	store := &Local{Dir: os.TempDir()}
+	for _, key := range []string{"", "../../etc/passwd", "zz"} {
+		if _, err := store.Get(key); err == nil {
+			t.Errorf("Expected error for key %q", key)
+		}
+		if err := store.Delete(key); err == nil {
+			t.Errorf("Expected delete error for key %q", key)
+		}
+	}
+}
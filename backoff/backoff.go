@@ -0,0 +1,18 @@
+package backoff
+
+import (
+	"time"
+)
+
+// Delay returns the wait before the next try after the given number of
+// failed attempts: base, 2*base, 4*base... capped at max
+func Delay(attempts int, base, max time.Duration) (d time.Duration) {
+	d = base
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return
+}
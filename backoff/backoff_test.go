@@ -0,0 +1,25 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	base, max := 5*time.Minute, time.Hour
+	for _, test := range []struct {
+		Attempts int
+		Delay    time.Duration
+	}{
+		{1, 5 * time.Minute},
+		{2, 10 * time.Minute},
+		{3, 20 * time.Minute},
+		{4, 40 * time.Minute},
+		{5, time.Hour},
+		{50, time.Hour},
+	} {
+		if d := Delay(test.Attempts, base, max); d != test.Delay {
+			t.Errorf("Attempt %d: Expected %s, got %s", test.Attempts, test.Delay, d)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package entity
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Entity types
+const (
+	Organization = "organization"
+	Person       = "person"
+	Place        = "place"
+)
+
+// Match is an entity found in a document
+type Match struct {
+	Name  string // Canonical name, even when matched by an alias
+	Type  string
+	Count int
+}
+
+type entry struct {
+	Name          string
+	Type          string
+	Phrase        string // Original-case phrase, used when CaseSensitive
+	CaseSensitive bool
+}
+
+// Gazetteer finds known entities in text by matching whole-word phrases. The
+// longest phrase wins when several overlap, so "Bank of America" is preferred
+// to "America".
+type Gazetteer struct {
+	phrases map[string][]entry
+	longest int
+}
+
+func NewGazetteer() *Gazetteer {
+	return &Gazetteer{phrases: make(map[string][]entry)}
+}
+
+func ValidType(t string) bool {
+	switch t {
+	case Organization, Person, Place:
+		return true
+	}
+	return false
+}
+
+// Add registers name (and any aliases) as an entity of type t. Case
+// sensitive entities are useful for short names which are also common words
+// (e.g. "Apple", "Box").
+func (g *Gazetteer) Add(name, t string, aliases []string, caseSensitive bool) {
+	for _, phrase := range append([]string{name}, aliases...) {
+		tokens := tokenize(phrase)
+		if len(tokens) == 0 {
+			continue
+		}
+		key := strings.ToLower(strings.Join(tokens, " "))
+		g.phrases[key] = append(g.phrases[key], entry{
+			Name:          name,
+			Type:          t,
+			Phrase:        strings.Join(tokens, " "),
+			CaseSensitive: caseSensitive,
+		})
+		if len(tokens) > g.longest {
+			g.longest = len(tokens)
+		}
+	}
+}
+
+func (g *Gazetteer) Len() int {
+	return len(g.phrases)
+}
+
+// Find returns the entities mentioned in text in the order they first appear
+func (g *Gazetteer) Find(text []byte) (matches []Match) {
+	tokens := tokenize(string(text))
+	lower := make([]string, len(tokens))
+	for i := range tokens {
+		lower[i] = strings.ToLower(tokens[i])
+	}
+
+	index := make(map[string]int)
+	for i := 0; i < len(tokens); {
+		n := g.match(tokens, lower, i)
+		if n == 0 {
+			i++
+			continue
+		}
+		e := g.lookup(tokens[i:i+n], lower[i:i+n])
+		if idx, ok := index[e.Name]; ok {
+			matches[idx].Count++
+		} else {
+			index[e.Name] = len(matches)
+			matches = append(matches, Match{Name: e.Name, Type: e.Type, Count: 1})
+		}
+		i += n
+	}
+	return
+}
+
+// Canonical returns the name of the entity registered under name or one of
+// its aliases. Case sensitive entities are matched regardless of case, as
+// names typed into a search rarely carry the capitalization of the text.
+func (g *Gazetteer) Canonical(name string) (string, bool) {
+	tokens := tokenize(name)
+	lower := make([]string, len(tokens))
+	for i := range tokens {
+		lower[i] = strings.ToLower(tokens[i])
+	}
+	if e := g.lookup(tokens, lower); e != nil {
+		return e.Name, true
+	}
+	if entries := g.phrases[strings.Join(lower, " ")]; len(entries) > 0 {
+		return entries[0].Name, true
+	}
+	return "", false
+}
+
+// Returns the length of the longest phrase starting at i, or zero
+func (g *Gazetteer) match(tokens, lower []string, i int) int {
+	for n := g.longest; n > 0; n-- {
+		if i+n > len(tokens) {
+			continue
+		}
+		if g.lookup(tokens[i:i+n], lower[i:i+n]) != nil {
+			return n
+		}
+	}
+	return 0
+}
+
+func (g *Gazetteer) lookup(tokens, lower []string) *entry {
+	entries, ok := g.phrases[strings.Join(lower, " ")]
+	if !ok {
+		return nil
+	}
+	phrase := strings.Join(tokens, " ")
+	for i := range entries {
+		if !entries[i].CaseSensitive || entries[i].Phrase == phrase {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// Splits on anything other than letters, digits and ampersands so "AT&T"
+// stays together while "U.S." becomes "U S" on both sides of the match
+func tokenize(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '&'
+	})
+}
@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"testing"
+)
+
+func testGazetteer() *Gazetteer {
+	g := NewGazetteer()
+	g.Add("Bank of America", Organization, []string{"BofA"}, false)
+	g.Add("America", Place, nil, false)
+	g.Add("AT&T", Organization, nil, false)
+	g.Add("Apple", Organization, nil, true)
+	g.Add("Tim Cook", Person, nil, false)
+	g.Add("United States", Place, []string{"U.S."}, false)
+	return g
+}
+
+func TestFind(t *testing.T) {
+	text := []byte(`Bank of America and AT&T said Tuesday that BofA would expand in the U.S. An apple a day; Apple's Tim Cook visited America.`)
+	expect := []Match{
+		{"Bank of America", Organization, 2},
+		{"AT&T", Organization, 1},
+		{"United States", Place, 1},
+		{"Apple", Organization, 1},
+		{"Tim Cook", Person, 1},
+		{"America", Place, 1},
+	}
+	matches := testGazetteer().Find(text)
+	if len(matches) != len(expect) {
+		t.Fatalf("Expected %d matches, got %d: %+v", len(expect), len(matches), matches)
+	}
+	for i := range expect {
+		if matches[i] != expect[i] {
+			t.Errorf("[%d] Expected %+v got %+v", i, expect[i], matches[i])
+		}
+	}
+}
+
+func TestFindNone(t *testing.T) {
+	if matches := testGazetteer().Find([]byte("Nothing to see here")); len(matches) != 0 {
+		t.Errorf("Expected no matches, got %+v", matches)
+	}
+	if matches := NewGazetteer().Find([]byte("Bank of America")); len(matches) != 0 {
+		t.Errorf("Empty gazetteer matched %+v", matches)
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	g := testGazetteer()
+	for name, expect := range map[string]string{
+		"bofa":            "Bank of America",
+		"Bank of America": "Bank of America",
+		"u.s.":            "United States",
+		"apple":           "Apple",
+		"Tim  Cook":       "Tim Cook",
+		"Microsoft":       "",
+	} {
+		canonical, ok := g.Canonical(name)
+		if canonical != expect || ok != (expect != "") {
+			t.Errorf("%q: Expected %q, got %q (%v)", name, expect, canonical, ok)
+		}
+	}
+}
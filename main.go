@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	_ "github.com/300brand/coverageservices/Article"
 	_ "github.com/300brand/coverageservices/Feed"
@@ -41,6 +42,9 @@ func init() {
 	gob.Register(new(bson.D))
 	gob.Register(new(bson.ObjectId))
 	gob.Register([]bson.ObjectId{})
+	gob.Register(time.Time{})
+	// JSON arrays in publication updates
+	gob.Register([]interface{}{})
 }
 
 func main() {
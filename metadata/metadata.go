@@ -0,0 +1,303 @@
+package metadata
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Metadata is what publishers describe about an article in the document head
+type Metadata struct {
+	Title     string
+	Author    string
+	Published time.Time
+	Image     string
+}
+
+var (
+	reMeta   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	reAttr   = regexp.MustCompile(`(?is)([a-z][a-z0-9:._-]*)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	reLink   = regexp.MustCompile(`(?is)<link\s[^>]*>`)
+	reAnchor = regexp.MustCompile(`(?is)<a\s[^>]*>`)
+	reBody   = regexp.MustCompile(`(?i)</head\s*>|<body[\s>]`)
+	reJSONLD = regexp.MustCompile(`(?is)<script[^>]+type\s*=\s*["']?application/ld\+json["']?[^>]*>(.*?)</script>`)
+
+	// schema.org types which describe an article
+	articleTypes = map[string]bool{
+		"Article":              true,
+		"NewsArticle":          true,
+		"ReportageNewsArticle": true,
+		"AnalysisNewsArticle":  true,
+		"BlogPosting":          true,
+		"TechArticle":          true,
+		"OpinionNewsArticle":   true,
+		"LiveBlogPosting":      true,
+		"ScholarlyArticle":     true,
+		"Report":               true,
+	}
+
+	// Meta names checked in order, first non-empty value wins
+	titleMeta  = []string{"og:title", "twitter:title", "dc.title", "title"}
+	authorMeta = []string{"article:author", "author", "dc.creator", "byl", "sailthru.author", "parsely-author"}
+	dateMeta   = []string{
+		"article:published_time",
+		"og:published_time",
+		"datepublished",
+		"date",
+		"pubdate",
+		"publishdate",
+		"publish-date",
+		"dc.date.issued",
+		"dc.date",
+		"sailthru.date",
+		"parsely-pub-date",
+	}
+	imageMeta = []string{"og:image", "og:image:url", "twitter:image", "twitter:image:src"}
+
+	// Hrefs which look like another page of the same article: ?page=2,
+	// /page/2, story-2.html, story_p2.html, /2/
+	rePagination = regexp.MustCompile(`(?i)(?:[?&](?:page|pg|p|paged|pagenum)=\d+|/page/?\d+/?$|[/_-]p?\d{1,3}(?:\.[a-z]+)?/?$)`)
+
+	dateLayouts = []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05Z0700",
+		"2006-01-02T15:04:05.000Z0700",
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+		"20060102",
+		time.RFC1123,
+		time.RFC1123Z,
+		"January 2, 2006",
+		"Jan 2, 2006",
+	}
+)
+
+// Extract reads JSON-LD, OpenGraph and standard meta tags. JSON-LD is
+// preferred as it is the most structured, then OpenGraph, then everything
+// else.
+func Extract(doc []byte) (m Metadata) {
+	fromJSONLD(doc, &m)
+
+	tags := metaTags(doc)
+	if m.Title == "" {
+		m.Title = first(tags, titleMeta)
+	}
+	if m.Author == "" {
+		// article:author is frequently a link to a profile page rather than
+		// a name
+		for _, name := range authorMeta {
+			if v := tags[name]; v != "" && !isURL(v) {
+				m.Author = v
+				break
+			}
+		}
+	}
+	if m.Published.IsZero() {
+		for _, name := range dateMeta {
+			if t := parseDate(tags[name]); !t.IsZero() {
+				m.Published = t
+				break
+			}
+		}
+	}
+	if m.Image == "" {
+		m.Image = first(tags, imageMeta)
+	}
+	return
+}
+
+func first(tags map[string]string, names []string) string {
+	for _, name := range names {
+		if v := tags[name]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// Collects <meta> name/property/itemprop => content, keeping the first value
+// for each lowercased name
+func metaTags(doc []byte) (tags map[string]string) {
+	tags = make(map[string]string)
+	for _, tag := range reMeta.FindAll(doc, -1) {
+		attrs := attributes(tag)
+		content := strings.TrimSpace(html.UnescapeString(attrs["content"]))
+		if content == "" {
+			continue
+		}
+		for _, key := range []string{"property", "name", "itemprop"} {
+			name := strings.ToLower(strings.TrimSpace(attrs[key]))
+			if name == "" {
+				continue
+			}
+			if _, exists := tags[name]; !exists {
+				tags[name] = content
+			}
+		}
+	}
+	return
+}
+
+// Lowercased attribute name => raw value
+func attributes(tag []byte) (attrs map[string]string) {
+	attrs = make(map[string]string)
+	for _, a := range reAttr.FindAllSubmatch(tag, -1) {
+		attrs[strings.ToLower(string(a[1]))] = string(a[2]) + string(a[3]) + string(a[4])
+	}
+	return
+}
+
+func fromJSONLD(doc []byte, m *Metadata) {
+	for _, script := range reJSONLD.FindAllSubmatch(doc, -1) {
+		var v interface{}
+		if err := json.Unmarshal(script[1], &v); err != nil {
+			continue
+		}
+		obj := findArticle(v)
+		if obj == nil {
+			continue
+		}
+		if m.Title == "" {
+			m.Title = strings.TrimSpace(str(obj["headline"]))
+		}
+		if m.Author == "" {
+			m.Author = names(obj["author"])
+		}
+		if m.Published.IsZero() {
+			m.Published = parseDate(str(obj["datePublished"]))
+		}
+		if m.Image == "" {
+			m.Image = imageURL(obj["image"])
+		}
+	}
+}
+
+// Walks a JSON-LD value (object, array or @graph) looking for an article
+func findArticle(v interface{}) map[string]interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, item := range t {
+			if obj := findArticle(item); obj != nil {
+				return obj
+			}
+		}
+	case map[string]interface{}:
+		if isArticle(t["@type"]) {
+			return t
+		}
+		if graph, ok := t["@graph"]; ok {
+			return findArticle(graph)
+		}
+	}
+	return nil
+}
+
+func isArticle(t interface{}) bool {
+	switch v := t.(type) {
+	case string:
+		return articleTypes[v]
+	case []interface{}:
+		for _, s := range v {
+			if isArticle(s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func str(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// Authors may be a string, a Person object or a list of either
+func names(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case map[string]interface{}:
+		return strings.TrimSpace(str(t["name"]))
+	case []interface{}:
+		all := make([]string, 0, len(t))
+		for _, item := range t {
+			if name := names(item); name != "" {
+				all = append(all, name)
+			}
+		}
+		return strings.Join(all, ", ")
+	}
+	return ""
+}
+
+// Images may be a URL, an ImageObject or a list of either
+func imageURL(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case map[string]interface{}:
+		return str(t["url"])
+	case []interface{}:
+		for _, item := range t {
+			if u := imageURL(item); u != "" {
+				return u
+			}
+		}
+	}
+	return ""
+}
+
+func parseDate(s string) (t time.Time) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return
+	}
+	for _, layout := range dateLayouts {
+		var err error
+		if t, err = time.Parse(layout, s); err == nil {
+			return
+		}
+	}
+	return time.Time{}
+}
+
+// NextPage returns the href of the <link rel="next"> in the head, as used by
+// paginated articles. Failing that, an <a rel="next"> counts only when its
+// href looks like pagination, since sites also mark "next story" links that
+// way. The href may be relative.
+func NextPage(doc []byte) string {
+	head := doc
+	if loc := reBody.FindIndex(doc); loc != nil {
+		head = doc[:loc[0]]
+	}
+	for _, tag := range reLink.FindAll(head, -1) {
+		if href := relNext(tag); href != "" {
+			return href
+		}
+	}
+	for _, tag := range reAnchor.FindAll(doc, -1) {
+		if href := relNext(tag); href != "" && rePagination.MatchString(href) {
+			return href
+		}
+	}
+	return ""
+}
+
+// Returns the tag's href when it is marked rel="next"
+func relNext(tag []byte) string {
+	attrs := attributes(tag)
+	for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+		if rel == "next" {
+			return strings.TrimSpace(html.UnescapeString(attrs["href"]))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package metadata
+
+import (
+	"testing"
+	"time"
+)
+
+var jsonLD = []byte(`<html><head>
+<meta property="og:title" content="OG Title">
+<script type="application/ld+json">
+{"@context":"http://schema.org","@graph":[
+	{"@type":"WebSite","name":"Example"},
+	{"@type":"NewsArticle","headline":"LD Headline","datePublished":"2014-11-03T08:15:00-05:00",
+	 "author":[{"@type":"Person","name":"Jane Doe"},{"@type":"Person","name":"John Roe"}],
+	 "image":{"@type":"ImageObject","url":"http://example.com/lead.jpg"}}
+]}
+</script>
+</head><body></body></html>`)
+
+var openGraph = []byte(`<html><head>
+<meta property="og:title" content="Tom &amp; Jerry Return">
+<meta property="article:author" content="https://www.facebook.com/janedoe">
+<meta name="author" content='Jane Doe'>
+<meta property="article:published_time" content="2014-11-03T13:15:00Z" />
+<meta property="og:image" content="http://example.com/og.jpg">
+</head><body></body></html>`)
+
+var plainMeta = []byte(`<html><head>
+<META NAME="title" CONTENT="Plain Title">
+<meta name="pubdate" content="2014-11-03">
+<meta content="Sam Smith" name="byl">
+</head></html>`)
+
+func TestJSONLD(t *testing.T) {
+	m := Extract(jsonLD)
+	if m.Title != "LD Headline" {
+		t.Errorf("Title: %q", m.Title)
+	}
+	if m.Author != "Jane Doe, John Roe" {
+		t.Errorf("Author: %q", m.Author)
+	}
+	if !m.Published.Equal(time.Date(2014, 11, 3, 13, 15, 0, 0, time.UTC)) {
+		t.Errorf("Published: %s", m.Published)
+	}
+	if m.Image != "http://example.com/lead.jpg" {
+		t.Errorf("Image: %q", m.Image)
+	}
+}
+
+func TestOpenGraph(t *testing.T) {
+	m := Extract(openGraph)
+	if m.Title != "Tom & Jerry Return" {
+		t.Errorf("Title: %q", m.Title)
+	}
+	if m.Author != "Jane Doe" {
+		t.Errorf("Author: %q", m.Author)
+	}
+	if !m.Published.Equal(time.Date(2014, 11, 3, 13, 15, 0, 0, time.UTC)) {
+		t.Errorf("Published: %s", m.Published)
+	}
+	if m.Image != "http://example.com/og.jpg" {
+		t.Errorf("Image: %q", m.Image)
+	}
+}
+
+func TestPlainMeta(t *testing.T) {
+	m := Extract(plainMeta)
+	if m.Title != "Plain Title" {
+		t.Errorf("Title: %q", m.Title)
+	}
+	if m.Author != "Sam Smith" {
+		t.Errorf("Author: %q", m.Author)
+	}
+	if !m.Published.Equal(time.Date(2014, 11, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Published: %s", m.Published)
+	}
+	if m.Image != "" {
+		t.Errorf("Image: %q", m.Image)
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	tests := []struct {
+		Doc  string
+		Next string
+	}{
+		{`<head><link rel="next" href="/story?page=2"></head>`, "/story?page=2"},
+		{`<a href="story-3.html" rel="nofollow next">Next</a>`, "story-3.html"},
+		{`<a rel="prev" href="story?page=1">Prev</a> <a href="story?a=1&amp;page=3" rel=next>Next</a>`, "story?a=1&page=3"},
+		{`<a href="/2014/05/story/page/2" rel="next">Next</a>`, "/2014/05/story/page/2"},
+		{`<link rel="stylesheet" href="style.css">`, ""},
+		// Only the head's link counts, and only pagination-like anchors
+		{`<head></head><body><link rel="next" href="/story?page=2"></body>`, ""},
+		{`<a href="/news/another-story" rel="next">Next story</a>`, ""},
+	}
+	for i, test := range tests {
+		if next := NextPage([]byte(test.Doc)); next != test.Next {
+			t.Errorf("[%d] Expected %q got %q", i, test.Next, next)
+		}
+	}
+}
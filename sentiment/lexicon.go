@@ -0,0 +1,160 @@
+package sentiment
+
+var negators = map[string]bool{
+	"not":     true,
+	"no":      true,
+	"never":   true,
+	"neither": true,
+	"nor":     true,
+	"without": true,
+	"cannot":  true,
+	"lack":    true,
+	"lacks":   true,
+}
+
+var boosters = map[string]float64{
+	"very":          0.3,
+	"extremely":     0.5,
+	"highly":        0.3,
+	"really":        0.3,
+	"remarkably":    0.4,
+	"significantly": 0.3,
+	"particularly":  0.2,
+	"hugely":        0.4,
+	"slightly":      -0.3,
+	"somewhat":      -0.3,
+	"barely":        -0.4,
+}
+
+// Word => valence, -3 (very negative) to 3 (very positive). Geared toward
+// business and technology coverage.
+var lexicon = map[string]float64{
+	// Positive
+	"accelerate":   1,
+	"achieve":      1.5,
+	"achievement":  2,
+	"acclaimed":    2,
+	"advance":      1,
+	"advantage":    1.5,
+	"award":        2,
+	"awarded":      2,
+	"best":         2.5,
+	"beat":         1,
+	"benefit":      1.5,
+	"benefits":     1.5,
+	"boost":        1.5,
+	"breakthrough": 2.5,
+	"celebrate":    2.5,
+	"confident":    1.5,
+	"effective":    1.5,
+	"efficient":    1.5,
+	"excellent":    3,
+	"exceptional":  3,
+	"excited":      2,
+	"exciting":     2,
+	"expand":       1,
+	"expansion":    1,
+	"favorable":    2,
+	"gain":         1.5,
+	"gains":        1.5,
+	"good":         2,
+	"great":        3,
+	"grow":         1,
+	"growth":       1.5,
+	"happy":        2.5,
+	"improve":      1.5,
+	"improved":     1.5,
+	"improvement":  1.5,
+	"innovative":   2,
+	"leader":       1,
+	"leading":      1,
+	"love":         3,
+	"milestone":    1.5,
+	"outperform":   2,
+	"partnership":  1,
+	"pleased":      2,
+	"popular":      1.5,
+	"positive":     2,
+	"praise":       2.5,
+	"profit":       1.5,
+	"profitable":   2,
+	"progress":     1.5,
+	"record":       1,
+	"recover":      1,
+	"recovery":     1,
+	"reliable":     2,
+	"robust":       1.5,
+	"secure":       1,
+	"strong":       2,
+	"stronger":     2,
+	"succeed":      2,
+	"success":      2.5,
+	"successful":   2.5,
+	"surge":        1.5,
+	"thrive":       2,
+	"top":          1,
+	"win":          2.5,
+	"wins":         2.5,
+	"winner":       2.5,
+
+	// Negative
+	"attack":        -2,
+	"bad":           -2.5,
+	"bankrupt":      -3,
+	"bankruptcy":    -3,
+	"breach":        -2.5,
+	"bug":           -1.5,
+	"collapse":      -2.5,
+	"complaint":     -2,
+	"concern":       -1,
+	"concerns":      -1,
+	"controversy":   -2,
+	"crash":         -2.5,
+	"crisis":        -3,
+	"criticism":     -2,
+	"criticized":    -2,
+	"damage":        -2,
+	"decline":       -1.5,
+	"declined":      -1.5,
+	"delay":         -1.5,
+	"delayed":       -1.5,
+	"disappointing": -2,
+	"downturn":      -2,
+	"fail":          -2.5,
+	"failed":        -2.5,
+	"failure":       -2.5,
+	"fall":          -1,
+	"fell":          -1,
+	"fined":         -2,
+	"fraud":         -3,
+	"hack":          -2,
+	"hacked":        -2.5,
+	"layoffs":       -2.5,
+	"lawsuit":       -2,
+	"lose":          -2,
+	"loss":          -2,
+	"losses":        -2,
+	"miss":          -1.5,
+	"missed":        -1.5,
+	"negative":      -2,
+	"outage":        -2.5,
+	"poor":          -2,
+	"problem":       -2,
+	"problems":      -2,
+	"recall":        -2,
+	"risk":          -1,
+	"risks":         -1,
+	"scandal":       -3,
+	"slump":         -2,
+	"struggle":      -2,
+	"sued":          -2,
+	"terrible":      -3,
+	"threat":        -2,
+	"vulnerability": -2,
+	"vulnerable":    -2,
+	"warning":       -1.5,
+	"weak":          -2,
+	"weaker":        -2,
+	"worse":         -2.5,
+	"worst":         -3,
+}
@@ -0,0 +1,131 @@
+package sentiment
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Scores within this distance of zero are considered neutral
+const Neutral = 0.05
+
+// Normalization constant; approximates the max expected raw value so a
+// couple of strong words don't immediately pin the score at +/-1
+const alpha = 15
+
+// Number of words after a negator which have their polarity flipped
+const negationWindow = 3
+
+// Sentence and its score
+type Sentence struct {
+	Text  string
+	Score float64
+}
+
+// Score returns the sentiment of text between -1 (negative) and 1
+// (positive)
+func Score(text string) float64 {
+	return normalize(raw(words(text)))
+}
+
+// Sentences returns each sentence of text which mentions any of the terms
+// (case-insensitive), scored individually. At most max sentences are
+// returned, zero means no limit.
+func Sentences(text string, terms []string, max int) (sentences []Sentence) {
+	lower := make([]string, 0, len(terms))
+	for _, t := range terms {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			lower = append(lower, t)
+		}
+	}
+	if len(lower) == 0 {
+		return
+	}
+	for _, s := range Split(text) {
+		ls := strings.ToLower(s)
+		for _, t := range lower {
+			if !strings.Contains(ls, t) {
+				continue
+			}
+			sentences = append(sentences, Sentence{Text: s, Score: Score(s)})
+			break
+		}
+		if max > 0 && len(sentences) == max {
+			break
+		}
+	}
+	return
+}
+
+// Split breaks text into sentences on terminal punctuation followed by a
+// space and on line breaks
+func Split(text string) (sentences []string) {
+	runes := []rune(text)
+	start := 0
+	flush := func(end int) {
+		if s := strings.TrimSpace(string(runes[start:end])); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = end
+	}
+	for i, r := range runes {
+		switch {
+		case r == '\n':
+			flush(i + 1)
+		case r == '.' || r == '!' || r == '?':
+			if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) {
+				flush(i + 1)
+			}
+		}
+	}
+	flush(len(runes))
+	return
+}
+
+func words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+}
+
+// Sums the lexicon values of words, flipping polarity after negators and
+// strengthening words after boosters
+func raw(words []string) (sum float64) {
+	negate := 0
+	boost := 0.0
+	for _, w := range words {
+		if negators[w] || strings.HasSuffix(w, "n't") {
+			negate = negationWindow
+			continue
+		}
+		if b, ok := boosters[w]; ok {
+			boost = b
+			continue
+		}
+		v, ok := lexicon[w]
+		if !ok {
+			if negate > 0 {
+				negate--
+			}
+			continue
+		}
+		if boost != 0 {
+			v += math.Copysign(boost, v)
+			boost = 0
+		}
+		if negate > 0 {
+			// "not good" is not as bad as "bad"
+			v *= -0.75
+			negate = 0
+		}
+		sum += v
+	}
+	return
+}
+
+func normalize(sum float64) float64 {
+	if sum == 0 {
+		return 0
+	}
+	return sum / math.Sqrt(sum*sum+alpha)
+}
@@ -0,0 +1,74 @@
+package sentiment
+
+import (
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		Text string
+		Sign int
+	}{
+		{"The launch was a great success.", 1},
+		{"Quarterly losses and layoffs deepen the crisis.", -1},
+		{"The update is not good.", -1},
+		{"The results were not bad at all.", 1},
+		{"The company released its report on Tuesday.", 0},
+		{"", 0},
+	}
+	for i, test := range tests {
+		score := Score(test.Text)
+		if score < -1 || score > 1 {
+			t.Errorf("[%d] Score out of range: %f", i, score)
+		}
+		sign := 0
+		switch {
+		case score > Neutral:
+			sign = 1
+		case score < -Neutral:
+			sign = -1
+		}
+		if sign != test.Sign {
+			t.Errorf("[%d] %q scored %f, expected sign %d", i, test.Text, score, test.Sign)
+		}
+	}
+}
+
+func TestBoost(t *testing.T) {
+	if Score("very good") <= Score("good") {
+		t.Errorf("Booster did not increase score")
+	}
+}
+
+func TestSentences(t *testing.T) {
+	text := "Acme posted record profit. Rivals struggle with losses!\nAcme's CEO is worried about the risk of a breach. The weather was fine."
+	sentences := Sentences(text, []string{"acme"}, 0)
+	if len(sentences) != 2 {
+		t.Fatalf("Expected 2 sentences, got %+v", sentences)
+	}
+	if sentences[0].Text != "Acme posted record profit." || sentences[0].Score <= 0 {
+		t.Errorf("First sentence: %+v", sentences[0])
+	}
+	if sentences[1].Score >= 0 {
+		t.Errorf("Second sentence: %+v", sentences[1])
+	}
+	if s := Sentences(text, []string{"acme"}, 1); len(s) != 1 {
+		t.Errorf("Max not applied: %+v", s)
+	}
+	if s := Sentences(text, nil, 0); len(s) != 0 {
+		t.Errorf("Expected no sentences without terms: %+v", s)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	sentences := Split("Version 2.0 shipped. Was it late? Yes!\nNew line")
+	expect := []string{"Version 2.0 shipped.", "Was it late?", "Yes!", "New line"}
+	if len(sentences) != len(expect) {
+		t.Fatalf("Expected %q got %q", expect, sentences)
+	}
+	for i := range expect {
+		if sentences[i] != expect[i] {
+			t.Errorf("[%d] Expected %q got %q", i, expect[i], sentences[i])
+		}
+	}
+}
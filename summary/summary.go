@@ -0,0 +1,170 @@
+package summary
+
+import (
+	"github.com/300brand/coverageservices/sentiment"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	damping    = 0.85
+	iterations = 30
+	// Ranking is quadratic in sentences, so only the opening of very long
+	// documents is considered
+	maxSentences = 200
+)
+
+// Lead returns the first n sentences of text
+func Lead(text string, n int) []string {
+	if n < 0 {
+		n = 0
+	}
+	sentences := sentiment.Split(text)
+	if len(sentences) > n {
+		sentences = sentences[:n]
+	}
+	return sentences
+}
+
+// Rank returns the n most central sentences of text, in their original
+// order, using a TextRank-style graph of word overlap between sentences
+func Rank(text string, n int) []string {
+	return Focused(text, nil, n)
+}
+
+// Focused works as Rank but picks sentences containing any of the terms
+// first. With no terms, or no sentence containing one, it is the same as Rank.
+// Only the first maxSentences sentences are candidates.
+func Focused(text string, terms []string, n int) []string {
+	if n < 0 {
+		n = 0
+	}
+	sentences := sentiment.Split(text)
+	if len(sentences) > maxSentences {
+		sentences = sentences[:maxSentences]
+	}
+	if len(sentences) <= n {
+		return sentences
+	}
+
+	matches := make([]bool, len(sentences))
+	for i, s := range sentences {
+		ls := strings.ToLower(s)
+		for _, t := range terms {
+			if t = strings.ToLower(strings.TrimSpace(t)); t != "" && strings.Contains(ls, t) {
+				matches[i] = true
+				break
+			}
+		}
+	}
+
+	order := &byRank{
+		idx:     make([]int, len(sentences)),
+		matches: matches,
+		scores:  rank(sentences),
+	}
+	for i := range order.idx {
+		order.idx[i] = i
+	}
+	sort.Stable(order)
+	top := order.idx[:n]
+	sort.Ints(top)
+
+	summary := make([]string, n)
+	for i, idx := range top {
+		summary[i] = sentences[idx]
+	}
+	return summary
+}
+
+// Sorts sentence indexes with term matches first, then by descending score
+type byRank struct {
+	idx     []int
+	matches []bool
+	scores  []float64
+}
+
+func (r *byRank) Len() int      { return len(r.idx) }
+func (r *byRank) Swap(i, j int) { r.idx[i], r.idx[j] = r.idx[j], r.idx[i] }
+func (r *byRank) Less(i, j int) bool {
+	a, b := r.idx[i], r.idx[j]
+	if r.matches[a] != r.matches[b] {
+		return r.matches[a]
+	}
+	return r.scores[a] > r.scores[b]
+}
+
+// PageRank over the sentence similarity graph
+func rank(sentences []string) []float64 {
+	words := make([]map[string]bool, len(sentences))
+	for i, s := range sentences {
+		words[i] = make(map[string]bool)
+		for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len(w) > 2 && !stopWords[w] {
+				words[i][w] = true
+			}
+		}
+	}
+
+	n := len(sentences)
+	weights := make([][]float64, n)
+	totals := make([]float64, n)
+	for i := range weights {
+		weights[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			w := similarity(words[i], words[j])
+			weights[i][j], weights[j][i] = w, w
+			totals[i] += w
+			totals[j] += w
+		}
+	}
+
+	scores := make([]float64, n)
+	for i := range scores {
+		scores[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+	for iter := 0; iter < iterations; iter++ {
+		for i := 0; i < n; i++ {
+			var sum float64
+			for j := 0; j < n; j++ {
+				if weights[j][i] > 0 {
+					sum += weights[j][i] / totals[j] * scores[j]
+				}
+			}
+			next[i] = (1-damping)/float64(n) + damping*sum
+		}
+		scores, next = next, scores
+	}
+	return scores
+}
+
+// Shared words normalized by sentence length, per the TextRank paper
+func similarity(a, b map[string]bool) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+	var shared float64
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	return shared / (math.Log(float64(len(a))) + math.Log(float64(len(b))))
+}
+
+var stopWords = map[string]bool{
+	"about": true, "after": true, "also": true, "and": true, "are": true,
+	"been": true, "but": true, "can": true, "for": true, "from": true,
+	"had": true, "has": true, "have": true, "her": true, "his": true,
+	"its": true, "more": true, "not": true, "one": true, "our": true,
+	"said": true, "she": true, "that": true, "the": true, "their": true,
+	"there": true, "they": true, "this": true, "was": true, "were": true,
+	"which": true, "will": true, "with": true, "would": true, "you": true,
+}
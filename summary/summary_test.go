@@ -0,0 +1,76 @@
+package summary
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var text = strings.Join([]string{
+	"Acme Corp announced a new cloud storage platform on Monday.",
+	"The weather in Boston was mild.",
+	"The cloud storage platform encrypts data and replicates storage across regions.",
+	"Analysts expect the storage platform to compete with larger cloud providers.",
+	"Lunch was served at noon.",
+	"Acme shares rose after the security audit praised its encryption.",
+}, " ")
+
+func TestLead(t *testing.T) {
+	expect := []string{
+		"Acme Corp announced a new cloud storage platform on Monday.",
+		"The weather in Boston was mild.",
+	}
+	if got := Lead(text, 2); !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected %q got %q", expect, got)
+	}
+	if got := Lead("One sentence.", 3); len(got) != 1 {
+		t.Errorf("Expected 1 sentence, got %q", got)
+	}
+}
+
+func TestRank(t *testing.T) {
+	got := Rank(text, 2)
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 sentences, got %q", got)
+	}
+	for _, s := range got {
+		if !strings.Contains(s, "storage") {
+			t.Errorf("Expected central sentences about storage, got %q", s)
+		}
+	}
+}
+
+func TestFocused(t *testing.T) {
+	got := Focused(text, []string{"encryption"}, 1)
+	expect := []string{"Acme shares rose after the security audit praised its encryption."}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("Expected %q got %q", expect, got)
+	}
+}
+
+func TestNegative(t *testing.T) {
+	if got := Lead(text, -1); len(got) != 0 {
+		t.Errorf("Lead: Expected no sentences, got %q", got)
+	}
+	if got := Rank(text, -1); len(got) != 0 {
+		t.Errorf("Rank: Expected no sentences, got %q", got)
+	}
+}
+
+func TestLongDocument(t *testing.T) {
+	sentences := make([]string, 5*maxSentences)
+	for i := range sentences {
+		sentences[i] = "The storage platform replicates data across regions number " + strings.Repeat("x", i%7+3) + "."
+	}
+	// Terms past the cap are out of reach
+	sentences[len(sentences)-1] = "Encryption was praised by the auditors."
+	got := Focused(strings.Join(sentences, " "), []string{"encryption"}, 3)
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 sentences, got %d", len(got))
+	}
+	for _, s := range got {
+		if strings.Contains(s, "Encryption") {
+			t.Errorf("Sentence beyond the first %d was considered: %q", maxSentences, s)
+		}
+	}
+}
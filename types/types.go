@@ -2,12 +2,76 @@ package types
 
 import (
 	"github.com/300brand/coverage"
+	"github.com/300brand/coverage/social"
 	"github.com/300brand/coverage/storage/mongo"
 	"labix.org/v2/mgo/bson"
 	"runtime"
 	"time"
 )
 
+type ArticleArchive struct {
+	Id        bson.ObjectId `bson:"_id"`
+	Key       string
+	Size      int
+	Truncated bool
+	Added     time.Time
+	Pages     []ArchivedPage // Pages after the first of a multi-page article
+}
+
+type ArchivedPage struct {
+	URL string
+	Key string
+}
+
+type ArchiveKeys struct {
+	Keys []string
+}
+
+type ArchivedHTML struct {
+	HTML []byte
+}
+
+type ArticleAttempts struct {
+	Id       bson.ObjectId `bson:"_id"`
+	Attempts int
+	Error    string
+	Updated  time.Time
+}
+
+type ArticleEntity struct {
+	Name  string
+	Key   string // Lowercased name for searching
+	Type  string
+	Count int
+}
+
+// Values extracted from an article which have no place in coverage.Article.
+// Saved alongside the article after each processing run.
+type ArticleExtras struct {
+	Id        bson.ObjectId `bson:"-"`
+	Entities  []ArticleEntity
+	Image     string   // Lead image URL
+	Truncated bool     // HTML was cut off at the publication's max file size
+	Pages     []string // URLs of every page of a multi-page article, in order
+	Sentiment float64  // -1 (negative) to 1 (positive)
+	Summary   []string // Extractive summary, in article order
+}
+
+// Stored extractive summaries keyed by article ID hex
+type ArticleSummaries struct {
+	Summaries map[string][]string
+}
+
+type ActivityQuery struct {
+	Publication bson.ObjectId
+	Days        int // Days of history ending today; 0 uses the configured default
+}
+
+type ArticleFailure struct {
+	Id    bson.ObjectId
+	Error string
+}
+
 type ClockCommand struct {
 	Command string
 	Tick    time.Duration
@@ -27,11 +91,46 @@ type DateSearch struct {
 	Query string
 }
 
+type DeadArticle struct {
+	Id       bson.ObjectId `bson:"_id"`
+	Article  coverage.Article
+	Attempts int
+	Error    string
+	Added    time.Time
+}
+
+type Entity struct {
+	Id            bson.ObjectId `bson:"_id"`
+	Name          string
+	Type          string
+	Aliases       []string
+	CaseSensitive bool
+}
+
+type GroupSearchCancel struct {
+	Id        bson.ObjectId `bson:"_id"`
+	Cancelled time.Time
+}
+
 type NewFeed struct {
 	PublicationId bson.ObjectId
 	URL           string
 }
 
+type DayCount struct {
+	Date     time.Time // Midnight UTC
+	Articles int
+}
+
+type FeedActivity struct {
+	Id           bson.ObjectId
+	URL          string
+	Deleted      bool
+	Articles     int       // Articles added during the period
+	LastArticle  time.Time // When the newest article from this feed was added
+	LastDownload time.Time
+}
+
 type Inc struct {
 	Id    bson.ObjectId
 	Delta int
@@ -59,12 +158,30 @@ type MultiArticles struct {
 	Articles []*coverage.Article
 }
 
+type MultiDeadArticles struct {
+	Query    MultiQuery
+	Total    int
+	Articles []*DeadArticle
+}
+
+type MultiEntities struct {
+	Query    MultiQuery
+	Total    int
+	Entities []*Entity
+}
+
 type MultiFeeds struct {
 	Query MultiQuery
 	Total int
 	Feeds []*coverage.Feed
 }
 
+type MultiPubCollections struct {
+	Query       MultiQuery
+	Total       int
+	Collections []*PubCollection
+}
+
 type MultiPubs struct {
 	Query        MultiQuery
 	Total        int
@@ -78,15 +195,274 @@ type Pub struct {
 	Feeds      []string
 }
 
+type PubImport struct {
+	Pubs   []Pub
+	DryRun bool // Report what would happen without saving anything
+}
+
+// Outcome of importing one publication; Status is one of created, updated,
+// unchanged or error
+type PubImportResult struct {
+	URL      string
+	Id       bson.ObjectId
+	Status   string
+	Error    string
+	Changed  []string // Fields changed on an existing publication
+	NewFeeds []string
+}
+
+type PubImportResults struct {
+	DryRun    bool
+	Created   int
+	Updated   int
+	Unchanged int
+	Errors    int
+	Results   []PubImportResult
+}
+
+type ReprocessAll struct {
+	Query       MultiQuery
+	Dates       startend
+	Archived    bool
+	Concurrency int
+}
+
+type ReprocessJob struct {
+	Id          bson.ObjectId `bson:"_id"`
+	Query       string        // Readable copy of the query; operators can't be stored
+	Archived    bool
+	Concurrency int
+	State       string
+	Cancelled   bool
+	Total       int
+	Processed   int
+	Failed      int
+	Failures    []ArticleFailure
+	Start       time.Time
+	Complete    time.Time
+}
+
+// Validated publication changes; nil fields are left untouched
+type PubChanges struct {
+	Id             bson.ObjectId
+	Title          *string
+	URL            *string
+	NumReaders     *int64
+	AuthorXPaths   *[]string
+	BodyXPaths     *[]string
+	DateXPaths     *[]string
+	TitleXPaths    *[]string
+	MaxFileSize    *int64
+	NextPageXPaths *[]string
+}
+
+// Named set of publications usable in searches
+type PubCollection struct {
+	Id             bson.ObjectId `bson:"_id"`
+	Name           string
+	Key            string // Lowercase name, unique
+	Description    string
+	PublicationIds []bson.ObjectId
+}
+
+type PubActivity struct {
+	Id           bson.ObjectId
+	Since        time.Time
+	Days         []DayCount // Articles per publish date, oldest first
+	Feeds        []FeedActivity
+	LastArticle  time.Time // Publish date of the most recent article
+	Extracted    int       // Articles saved with a body during the period
+	Failed       int       // Articles saved without a body during the period
+	DeadLettered int       // Articles given up on after failed downloads
+	Success      float64   // Extracted / (Extracted + Failed)
+}
+
+// Stored counters of a publication next to the actual counts
+type PubCount struct {
+	Id          bson.ObjectId
+	Title       string
+	NumFeeds    int
+	Feeds       int // Feeds not deleted
+	NumArticles int64
+	Articles    int64
+}
+
+type PubCounts struct {
+	Counts []PubCount
+}
+
+// Moves everything from the source publications into the target
+type PubMerge struct {
+	Target  bson.ObjectId
+	Sources []bson.ObjectId
+}
+
+type PubMergeResult struct {
+	Feeds          int
+	Articles       int
+	QueuedArticles int
+	Searches       int // Saved searches whose PublicationIds were rewritten
+	Readership     int // History entries moved; the target's own entries win
+}
+
+// Empty Ids checks every publication
+type PubReconcile struct {
+	Ids []bson.ObjectId
+	Fix bool // Overwrite the stored counters with the actual counts
+}
+
+type PubReconcileResults struct {
+	Checked       int
+	Fixed         int
+	Discrepancies []PubCount
+}
+
+// Counts from purging publications deleted before the retention period
+type PubPurge struct {
+	Publications int
+	Feeds        int
+	Articles     int
+	ArchiveKeys  []string // Archived HTML no longer referenced by any article
+}
+
+// Counts from soft-deleting or restoring a publication
+type PubRemoval struct {
+	Id             bson.ObjectId
+	Feeds          int // Feeds deleted or restored along with the publication
+	QueuedArticles int // Articles dropped from the processing queue
+}
+
+// Tags to add to and remove from a publication. Tags are stored lowercase.
+type PubTagChange struct {
+	Id     bson.ObjectId
+	Add    []string
+	Remove []string
+}
+
+type PubTags struct {
+	Id   bson.ObjectId
+	Tags []string
+}
+
+// Per-publication processing options, stored with the publication and edited
+// through Publication.Update as settings.maxfilesize and settings.nextpage
+type PubSettings struct {
+	Id          bson.ObjectId `bson:"-"`
+	MaxFileSize int64         // Truncate HTML beyond this size; 0 uses the downloader limit
+	NextPage    []string      // XPaths to the href of the next page link of multi-page articles
+}
+
+// Field changes to a publication, keyed by field name. See
+// Publication.Update for the editable fields.
+type PubUpdate struct {
+	Id     bson.ObjectId
+	Fields map[string]interface{}
+}
+
+// Left in place of a merged publication so its old ID still resolves
+type PubRedirect struct {
+	Id     bson.ObjectId `bson:"_id"`
+	Target bson.ObjectId
+	Merged time.Time
+}
+
+// Publication readership from the effective date until the next entry
+type Readership struct {
+	Id            bson.ObjectId `bson:"_id"`
+	PublicationId bson.ObjectId
+	Readers       int64
+	Effective     time.Time
+	Added         time.Time
+}
+
+type ReadershipHistory struct {
+	PublicationId bson.ObjectId
+	History       []Readership // Oldest first
+}
+
+// Publication and date to look up the readership of, usually an article's
+type ReadershipLookup struct {
+	PublicationId bson.ObjectId
+	Date          time.Time
+}
+
+type ReadershipLookups struct {
+	Lookups []ReadershipLookup
+}
+
+// Readers for each lookup, in the same order
+type ReadershipValues struct {
+	Readers []int64
+}
+
+// Index is the position of the rejected update
+type ReadershipError struct {
+	Index int
+	Error string
+}
+
+type ReadershipResults struct {
+	Saved  int
+	Errors []ReadershipError
+}
+
+type ReadershipUpdate struct {
+	PublicationId bson.ObjectId
+	Readers       int64
+	Effective     time.Time // Zero means now
+}
+
+type ReadershipUpdates struct {
+	Updates []ReadershipUpdate
+}
+
+type Reprocess struct {
+	Id       bson.ObjectId
+	Archived bool // Use the archived HTML instead of downloading again
+}
+
 type SearchQuery struct {
 	Q              string
 	Label          string
 	Notify         notify
 	Dates          startend
 	PublicationIds []bson.ObjectId
-	CaseSensitive  bool
-	Foreground     bool // During group queries, don't background the processing
-	Version        int  // Version 0 or 1: convert simple query format; 2: Use complex format
+	// Collection names and publication tags, resolved to PublicationIds by
+	// Search.Search. Excluded publications are dropped from the results.
+	Collections        []string
+	Tags               []string
+	ExcludeCollections []string
+	ExcludeTags        []string
+	Entities           []string // Only match articles mentioning all of these (names or aliases)
+	CaseSensitive      bool
+	Foreground         bool          // During group queries, don't background the processing
+	Version            int           // Version 0 or 1: convert simple query format; 2: Use complex format
+	GroupId            bson.ObjectId // Set by GroupSearch so cancelling the group cancels its searches
+}
+
+type SearchValidation struct {
+	Query   SearchQuery
+	Explain bool // Also build the mongosearch query and estimate its matches
+}
+
+type SearchValidationResult struct {
+	Valid      bool
+	V2         string      // Query after conversion from V1
+	Normalized string      // Parsed query tree
+	Terms      []string    // Positive terms, used for sentiment and summaries
+	Error      *QueryError // Set when the query is invalid
+	Explain    *SearchExplain
+}
+
+type QueryError struct {
+	Offset  int // Character offset into the V2 query; -1 when unknown
+	Message string
+}
+
+type SearchExplain struct {
+	Query    string // As sent to mongosearch
+	Buckets  int    // Publish dates searched
+	Estimate int    // Upper bound on matching articles; ignores AND and NOT
 }
 
 type SearchQueryResponse struct {
@@ -95,22 +471,128 @@ type SearchQueryResponse struct {
 }
 
 type SearchStatus struct {
-	Id bson.ObjectId
+	Id        bson.ObjectId
+	State     string // running, completed, failed, cancelled or interrupted
+	Start     time.Time
+	Completed time.Time
+	Results   int
+	Error     string
+}
+
+// Page of a search's results
+type SearchResultsQuery struct {
+	Id     bson.ObjectId
+	Offset int
+	Limit  int
+	Fields []string // Article fields to return, eg. "title" or "text.body"
 }
 
 type SearchResults struct {
 	Id        bson.ObjectId
 	Ready     bool
 	Completed time.Time
+	Total     int
+	Offset    int
 	Articles  []coverage.Article
 }
 
+type SearchSentiment struct {
+	Id      bson.ObjectId
+	Terms   []string // Terms used to pick sentences
+	Summary SentimentSummary
+	Results []ResultSentiment
+}
+
+type ResultSentiment struct {
+	Id        bson.ObjectId `bson:"_id"`
+	Sentiment float64
+	Sentences []SentenceScore // Sentences mentioning a search term
+}
+
+type SentenceScore struct {
+	Text  string
+	Score float64
+}
+
+type SentimentSummary struct {
+	Mean     float64
+	Positive int
+	Negative int
+	Neutral  int
+}
+
 type Set struct {
 	Id    bson.ObjectId
 	Key   string
 	Value interface{}
 }
 
+// Scheduled fetch of an article's social stats at Offset after publication
+type SocialPoll struct {
+	Id        bson.ObjectId `bson:"_id"`
+	ArticleId bson.ObjectId
+	URL       string
+	Offset    time.Duration
+	Due       time.Time
+	Attempts  int // Failed fetches so far
+}
+
+type SocialHistory struct {
+	ArticleId bson.ObjectId
+	Snapshots []SocialSnapshot // Oldest first
+}
+
+// Social stats of an article as of the fetch time
+type SocialSnapshot struct {
+	Id        bson.ObjectId `bson:"_id"`
+	ArticleId bson.ObjectId
+	Stats     social.Stats
+	Fetched   time.Time
+	Offset    time.Duration // Scheduled poll offset; 0 for on-demand fetches
+	Failed    []string      // Stats fields whose network failed; not counts
+}
+
+// Stats of an article along with the networks that could not be reached
+type SocialStats struct {
+	Stats  social.Stats
+	Errors map[string]string // Keyed by stats field; those counts are missing
+}
+
+type SocialSnapshots struct {
+	Snapshots []SocialSnapshot
+}
+
+// Total stats of a search's articles at each poll offset
+type SocialSeries struct {
+	SearchId bson.ObjectId
+	Points   []SocialPoint
+	Latest   SocialPoint // Most recent snapshot of every article
+}
+
+type SocialPoint struct {
+	Offset   time.Duration
+	Articles int // Articles with a snapshot at this offset
+	Stats    social.Stats
+}
+
+// Progress of fetching social stats for a search's articles
+type SocialProgress struct {
+	SearchId  bson.ObjectId
+	Total     int
+	Done      int // Articles whose stats were sent
+	Failed    int
+	Started   time.Time
+	Completed time.Time // Zero while running
+	Cancelled bool
+}
+
+// Article details needed to schedule social polls
+type SocialSchedule struct {
+	ArticleId bson.ObjectId
+	URL       string
+	Published time.Time
+}
+
 type Stat struct {
 	Name       string
 	Count      int
@@ -125,10 +607,44 @@ type Stats struct {
 }
 
 type GroupQuery struct {
-	Queries        []query
-	Notify         notify
-	Dates          startend
-	PublicationIds []bson.ObjectId
+	Queries            []query
+	Notify             notify
+	Dates              startend
+	PublicationIds     []bson.ObjectId
+	Collections        []string
+	Tags               []string
+	ExcludeCollections []string
+	ExcludeTags        []string
+	Entities           []string
+}
+
+type GroupSentiment struct {
+	Id       bson.ObjectId
+	Overall  SentimentSummary
+	Searches []SearchSentiment
+}
+
+// Sample pages for Publication.SuggestXPaths: the given URLs, or the
+// publication's most recent articles when there are none
+type XPathSample struct {
+	PublicationId bson.ObjectId
+	URLs          []string
+	Samples       int // Recent articles to use; 0 uses the configured default
+}
+
+type XPathCandidate struct {
+	XPath string
+	Hits  int
+	Rate  float64 // Fraction of samples matched
+}
+
+type XPathSuggestions struct {
+	Samples int
+	Errors  []string // Samples which could not be downloaded
+	Author  []XPathCandidate
+	Body    []XPathCandidate
+	Date    []XPathCandidate
+	Title   []XPathCandidate
 }
 
 type ViewPub struct {
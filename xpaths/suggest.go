@@ -0,0 +1,309 @@
+package xpaths
+
+import (
+	"fmt"
+	"github.com/300brand/coverageservices/metadata"
+	"github.com/moovweb/gokogiri"
+	"github.com/moovweb/gokogiri/html"
+	"github.com/moovweb/gokogiri/xml"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Candidates returned per field
+const maxCandidates = 5
+
+// Candidate XPath for one field and how many of the samples it matched
+type Candidate struct {
+	XPath string
+	Hits  int
+	Rate  float64 // Hits over the number of samples
+}
+
+type Suggestions struct {
+	Samples int
+	Author  []Candidate
+	Body    []Candidate
+	Date    []Candidate
+	Title   []Candidate
+}
+
+var (
+	reAuthorHint = regexp.MustCompile(`(?i)author|byline|writer|reporter`)
+	reDateHint   = regexp.MustCompile(`(?i)date|time|publish|posted`)
+	reTitleHint  = regexp.MustCompile(`(?i)title|headline|heading`)
+	reDigits     = regexp.MustCompile(`\d`)
+	reSpace      = regexp.MustCompile(`\s+`)
+
+	// Contents are not text
+	rawTags = map[string]bool{"script": true, "style": true, "noscript": true}
+)
+
+// Analyzes each page independently for candidate XPaths, then ranks every
+// candidate by the number of pages it selects text from. Pages are parsed and
+// evaluated with the same XPath engine article extraction uses, so the hit
+// rates reflect what extraction would find.
+func Suggest(pages [][]byte) (s Suggestions) {
+	s.Samples = len(pages)
+	candidates := map[string]map[string]bool{
+		"author": {},
+		"body":   {},
+		"date":   {},
+		"title":  {},
+	}
+	docs := make([]*html.HtmlDocument, 0, len(pages))
+	defer func() {
+		for _, doc := range docs {
+			doc.Free()
+		}
+	}()
+	for _, page := range pages {
+		doc, err := gokogiri.ParseHtml(page)
+		if err != nil {
+			continue
+		}
+		docs = append(docs, doc)
+		root := doc.Root()
+		if root == nil {
+			continue
+		}
+		title := metadata.Extract(page).Title
+		for field, xpaths := range map[string][]string{
+			"author": authorPaths(root),
+			"body":   bodyPaths(root),
+			"date":   datePaths(root),
+			"title":  titlePaths(root, title),
+		} {
+			for _, x := range xpaths {
+				candidates[field][x] = true
+			}
+		}
+	}
+
+	s.Author = rank(evaluate(docs, candidates["author"]), len(pages))
+	s.Body = rank(evaluate(docs, candidates["body"]), len(pages))
+	s.Date = rank(evaluate(docs, candidates["date"]), len(pages))
+	s.Title = rank(evaluate(docs, candidates["title"]), len(pages))
+	return
+}
+
+// Counts the documents each XPath selects text from. XPaths the engine
+// rejects or which never select anything are dropped.
+func evaluate(docs []*html.HtmlDocument, xpaths map[string]bool) (hits map[string]int) {
+	hits = make(map[string]int, len(xpaths))
+	for x := range xpaths {
+		for _, doc := range docs {
+			nodes, err := doc.Search(x)
+			if err != nil {
+				break
+			}
+			for _, n := range nodes {
+				if strings.TrimSpace(n.Content()) != "" {
+					hits[x]++
+					break
+				}
+			}
+		}
+		if hits[x] == 0 {
+			delete(hits, x)
+		}
+	}
+	return
+}
+
+// Short elements marked up as bylines
+func authorPaths(root xml.Node) (xpaths []string) {
+	walk(root, func(n xml.Node) {
+		if n.Attr("rel") == "author" || hinted(n, reAuthorHint) {
+			if l := len(allText(n)); l > 1 && l <= 100 {
+				xpaths = append(xpaths, expression(n, reAuthorHint))
+			}
+		}
+	})
+	return
+}
+
+// The element whose own paragraphs hold the most text
+func bodyPaths(root xml.Node) (xpaths []string) {
+	var (
+		best      xml.Node
+		bestScore int
+	)
+	walk(root, func(n xml.Node) {
+		score := 0
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			if tag(c) == "p" {
+				score += len(allText(c))
+			}
+		}
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	})
+	if best != nil && tag(best) != "html" && tag(best) != "body" {
+		xpaths = append(xpaths, expression(best, nil))
+	}
+	return
+}
+
+// <time> elements and short elements marked up as dates
+func datePaths(root xml.Node) (xpaths []string) {
+	walk(root, func(n xml.Node) {
+		if tag(n) == "time" || hinted(n, reDateHint) {
+			if l := len(allText(n)); l > 5 && l <= 60 {
+				xpaths = append(xpaths, expression(n, reDateHint))
+			}
+		}
+	})
+	return
+}
+
+// Headings whose text is the article title. Falls back to the <title> text,
+// then to every <h1>, when the page has no title metadata.
+func titlePaths(root xml.Node, title string) (xpaths []string) {
+	if title == "" {
+		walk(root, func(n xml.Node) {
+			if tag(n) == "title" && title == "" {
+				title = allText(n)
+			}
+		})
+	}
+	title = strings.ToLower(title)
+	walk(root, func(n xml.Node) {
+		if tag(n) == "title" || (!isHeading(n) && !hinted(n, reTitleHint)) {
+			return
+		}
+		text := strings.ToLower(allText(n))
+		if len(text) < 10 || len(text) > 300 {
+			return
+		}
+		if title == "" && tag(n) != "h1" {
+			return
+		}
+		// Titles often carry the site name: "Headline | Site"
+		if title == "" || text == title || strings.Contains(title, text) || strings.Contains(text, title) {
+			xpaths = append(xpaths, expression(n, reTitleHint))
+		}
+	})
+	return
+}
+
+// Calls fn for n and every element below it
+func walk(n xml.Node, fn func(xml.Node)) {
+	fn(n)
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if c.NodeType() == xml.XML_ELEMENT_NODE {
+			walk(c, fn)
+		}
+	}
+}
+
+// All text under the node, whitespace-collapsed. Scripts and styles are not
+// text.
+func allText(n xml.Node) string {
+	parts := []string{}
+	var collect func(xml.Node)
+	collect = func(n xml.Node) {
+		for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+			switch c.NodeType() {
+			case xml.XML_TEXT_NODE:
+				if t := strings.TrimSpace(reSpace.ReplaceAllString(c.Content(), " ")); t != "" {
+					parts = append(parts, t)
+				}
+			case xml.XML_ELEMENT_NODE:
+				if !rawTags[tag(c)] {
+					collect(c)
+				}
+			}
+		}
+	}
+	collect(n)
+	return strings.Join(parts, " ")
+}
+
+func tag(n xml.Node) string {
+	return strings.ToLower(n.Name())
+}
+
+func isHeading(n xml.Node) bool {
+	t := tag(n)
+	return len(t) == 2 && t[0] == 'h' && t[1] >= '1' && t[1] <= '6'
+}
+
+func hinted(n xml.Node, hint *regexp.Regexp) bool {
+	return hint.MatchString(n.Attr("itemprop")) || hint.MatchString(n.Attr("class")) || hint.MatchString(n.Attr("id"))
+}
+
+// Builds the most stable XPath for the node: itemprop, then id, then the class
+// matching the hint (or the first class), then the parent's path. IDs with
+// digits are usually per-article and skipped.
+func expression(n xml.Node, hint *regexp.Regexp) string {
+	t := tag(n)
+	if v := n.Attr("itemprop"); v != "" {
+		return fmt.Sprintf(`//%s[@itemprop=%s]`, t, literal(v))
+	}
+	if v := n.Attr("id"); v != "" && !reDigits.MatchString(v) {
+		return fmt.Sprintf(`//%s[@id=%s]`, t, literal(v))
+	}
+	if classes := strings.Fields(n.Attr("class")); len(classes) > 0 {
+		class := classes[0]
+		for _, c := range classes {
+			if hint != nil && hint.MatchString(c) {
+				class = c
+				break
+			}
+		}
+		return fmt.Sprintf(`//%s[contains(@class, %s)]`, t, literal(class))
+	}
+	if n.Attr("rel") == "author" {
+		return fmt.Sprintf(`//%s[@rel="author"]`, t)
+	}
+	parent := n.Parent()
+	if parent == nil || parent.NodeType() != xml.XML_ELEMENT_NODE || tag(parent) == "html" || tag(parent) == "body" {
+		return "//" + t
+	}
+	return expression(parent, nil) + "/" + t
+}
+
+// Quotes s as an XPath string literal. XPath 1.0 has no escapes, so values
+// holding both kinds of quote are pieced together with concat().
+func literal(s string) string {
+	switch {
+	case !strings.Contains(s, `"`):
+		return `"` + s + `"`
+	case !strings.Contains(s, "'"):
+		return "'" + s + "'"
+	}
+	parts := strings.Split(s, `"`)
+	for i := range parts {
+		parts[i] = `"` + parts[i] + `"`
+	}
+	return "concat(" + strings.Join(parts, `, '"', `) + ")"
+}
+
+func rank(hits map[string]int, samples int) (candidates []Candidate) {
+	for x, n := range hits {
+		candidates = append(candidates, Candidate{XPath: x, Hits: n, Rate: float64(n) / float64(samples)})
+	}
+	sort.Sort(byHits(candidates))
+	if len(candidates) > maxCandidates {
+		candidates = candidates[:maxCandidates]
+	}
+	return
+}
+
+type byHits []Candidate
+
+func (c byHits) Len() int      { return len(c) }
+func (c byHits) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+func (c byHits) Less(i, j int) bool {
+	if c[i].Hits != c[j].Hits {
+		return c[i].Hits > c[j].Hits
+	}
+	// Shorter paths are less tied to incidental markup
+	if len(c[i].XPath) != len(c[j].XPath) {
+		return len(c[i].XPath) < len(c[j].XPath)
+	}
+	return c[i].XPath < c[j].XPath
+}
@@ -0,0 +1,74 @@
+package xpaths
+
+import (
+	"fmt"
+	"testing"
+)
+
+const page = `<!DOCTYPE html>
+<html><head>
+<title>%s | Example News</title>
+<script>var x = "<div class='byline'>not markup</div>";</script>
+</head><body>
+<div id="nav"><a href="/">Home</a></div>
+<div class="article-wrap">
+<h1 class="headline">%s</h1>
+<span class="byline author-name">By %s</span>
+<time class="post-date" datetime="2014-03-0%dT10:00:00Z">March %d, 2014</time>
+<div class="story-body">
+<p>%s opened the quarterly meeting with a long discussion of storage pricing.</p>
+<p>Analysts on the call pressed for details about the roadmap and margins.<br>
+Several asked about the new data center.</p>
+<p>The company declined to give guidance beyond the current quarter.</p>
+</div>
+<div class="related"><p>Short</p></div>
+</div>
+</body></html>`
+
+func samples() (pages [][]byte) {
+	for i, s := range []struct{ Title, Author string }{
+		{"Acme Reports Record Quarter", "Jane Smith"},
+		{"Acme Opens Second Data Center", "John Doe"},
+		{"Storage Prices Keep Falling", "Jane Smith"},
+	} {
+		pages = append(pages, []byte(fmt.Sprintf(page, s.Title, s.Title, s.Author, i+1, i+1, s.Author)))
+	}
+	return
+}
+
+func TestSuggest(t *testing.T) {
+	s := Suggest(samples())
+	if s.Samples != 3 {
+		t.Errorf("Samples: %d", s.Samples)
+	}
+	for field, test := range map[string]struct {
+		Got    []Candidate
+		Expect string
+	}{
+		"Author": {s.Author, `//span[contains(@class, "byline")]`},
+		"Body":   {s.Body, `//div[contains(@class, "story-body")]`},
+		"Date":   {s.Date, `//time[contains(@class, "post-date")]`},
+		"Title":  {s.Title, `//h1[contains(@class, "headline")]`},
+	} {
+		if len(test.Got) == 0 {
+			t.Errorf("%s: no candidates", field)
+			continue
+		}
+		if best := test.Got[0]; best.XPath != test.Expect || best.Rate != 1 {
+			t.Errorf("%s: expected %s at 100%%, got %+v", field, test.Expect, test.Got)
+		}
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	for in, out := range map[string]string{
+		`byline`:        `"byline"`,
+		`it's`:          `"it's"`,
+		`say "hi"`:      `'say "hi"'`,
+		`it's "quoted"`: `concat("it's ", '"', "quoted", '"', "")`,
+	} {
+		if got := literal(in); got != out {
+			t.Errorf("%s: expected %s, got %s", in, out, got)
+		}
+	}
+}